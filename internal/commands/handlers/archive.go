@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// archiveResponse stores the compressed body of a fetch and drops the oldest archived ones beyond the configured limit
+func archiveResponse(ctx context.Context, s *config.State, feed database.Feed, response feedResponse) {
+	headers, err := json.Marshal(response.Header)
+	if err != nil {
+		s.LogError("Could not encode response headers of feed %s: %v", feed.Name, err)
+		return
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err = writer.Write(response.Body)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		s.LogError("Could not compress response of feed %s: %v", feed.Name, err)
+		return
+	}
+
+	err = s.Db.CreateFeedResponse(ctx, database.CreateFeedResponseParams{
+		FeedID:     feed.ID,
+		FetchedAt:  response.FetchedAt,
+		HttpStatus: int32(response.Status),
+		Headers:    headers,
+		Body:       compressed.Bytes(),
+	})
+	if err != nil {
+		s.LogError("Could not archive response of feed %s: %v", feed.Name, err)
+		return
+	}
+
+	err = s.Db.PruneFeedResponses(ctx, database.PruneFeedResponsesParams{
+		FeedID: feed.ID,
+		Keep:   int32(s.Config.Archive.KeepPerFeed()),
+	})
+	if err != nil {
+		s.LogError("Could not prune archived responses of feed %s: %v", feed.Name, err)
+	}
+}
+
+func HandlerReparse(s *config.State, cmd commands.Command) error {
+	s.LogDebug("Reparsing archived responses: args=%v", cmd.Args)
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("reparse expects a feed url or name: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	feeds, err := s.Db.GetFeedsByUrlOrName(ctx, cmd.Args[0])
+	if err != nil {
+		s.LogError("Failed to query feeds to reparse: %v", err)
+		return err
+	}
+
+	if len(feeds) == 0 {
+		s.LogError("No feed registered with url or name: %s", cmd.Args[0])
+		return fmt.Errorf("no feed has that url or name, %v", cmd.Args[0])
+	}
+
+	for _, feed := range feeds {
+		retention, err := retentionPolicy(s, feed)
+		if err != nil {
+			s.LogError("Could not read the retention policy of %s: %v", feed.Name, err)
+			return err
+		}
+
+		policy, err := firstFetchPolicy(s, feed)
+		if err != nil {
+			s.LogError("Could not read the first fetch policy of %s: %v", feed.Name, err)
+			return err
+		}
+
+		responses, err := s.Db.GetFeedResponses(ctx, feed.ID)
+		if err != nil {
+			s.LogError("Failed to query archived responses of %s: %v", feed.Name, err)
+			return err
+		}
+
+		if len(responses) == 0 {
+			s.LogInfo("%s: no archived responses, is the archive enabled in the config?", feed.Name)
+			continue
+		}
+
+		// Oldest first so the newest version of a post wins. An item without a date keeps the time it was
+		// first fetched, which is what it was stored with
+		var items []RSSItem
+		seen := map[string]int{}
+		for _, response := range responses {
+			rssFeed, err := parseArchivedResponse(response)
+			if err != nil {
+				s.LogError("%s: could not parse response fetched %s: %v", feed.Name, response.FetchedAt.Format("Jan 02, 2006 at 15:04"), err)
+				continue
+			}
+
+			// The first fetch only imported what the first fetch policy let through, responses before it
+			// were failed fetches which didn't import anything
+			responseItems := rssFeed.Channel.Items
+			if !feed.FirstFetchedAt.Valid || !response.FetchedAt.After(feed.FirstFetchedAt.Time) {
+				responseItems = firstFetchItems(policy, responseItems)
+			}
+
+			for _, item := range responseItems {
+				if i, ok := seen[item.Link]; ok {
+					item.FetchedAt = items[i].FetchedAt
+					items[i] = item
+					continue
+				}
+				seen[item.Link] = len(items)
+				items = append(items, item)
+			}
+		}
+
+		// Posts the feed's retention policy would prune aren't brought back
+		upserted := 0
+		for _, item := range retainedItems(retention, items) {
+			err := s.Db.UpsertPost(ctx, newPostParams(s, feed, item))
+			if err != nil {
+				s.LogError("Could not upsert the post for feed %s (%v). Item failed was %s: %v", feed.Name, feed.ID, item.Title, err)
+				continue
+			}
+			upserted++
+		}
+
+		s.LogInfo(ColorGreen+"%s:"+ColorReset+" reparsed %d archived responses, %d posts upserted", feed.Name, len(responses), upserted)
+	}
+
+	return nil
+}
+
+func parseArchivedResponse(response database.FeedResponse) (*RSSFeed, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(response.Body))
+	if err != nil {
+		return &RSSFeed{}, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return &RSSFeed{}, err
+	}
+
+	return parseFeed(data, response.FetchedAt)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+)
+
+func TestReparseAppliesFirstFetchPolicy(t *testing.T) {
+	s, _ := newTestState(t)
+	s.Config.Archive.Enabled = true
+	user := addUser(t, s, "bob")
+	now := time.Now()
+	newest := testItem{Title: "Newest", PubDate: now.Add(-time.Hour)}
+	middle := testItem{Title: "Middle", PubDate: now.Add(-2 * time.Hour)}
+	oldest := testItem{Title: "Oldest", PubDate: now.Add(-3 * time.Hour)}
+	server := serveFeed(t, middle, oldest)
+	feed := addTestFeed(t, s, user, server, "--first-fetch", "latest:1")
+
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("first scrape failed: %v", err)
+	}
+	server.Body = rssBody(newest)
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("second scrape failed: %v", err)
+	}
+	if got := postsFor(t, s, user); !slices.Equal(got, []string{"Newest", "Middle"}) {
+		t.Fatalf("expected the latest post of the first fetch and the new one, got %v", got)
+	}
+
+	if err := HandlerReparse(s, commands.Command{Name: "reparse", Args: []string{feed.Url}}); err != nil {
+		t.Fatalf("reparse failed: %v", err)
+	}
+	if got := postsFor(t, s, user); !slices.Equal(got, []string{"Newest", "Middle"}) {
+		t.Errorf("reparse shouldn't import what the first fetch left out, got %v", got)
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Kinds of credentials that can be stored against a feed
+const (
+	feedAuthBasic  = "basic"
+	feedAuthBearer = "bearer"
+	feedAuthCookie = "cookie"
+	feedAuthHeader = "header"
+)
+
+const feedAuthUsage = `usage: feedauth <url> basic <username> <password>
+       feedauth <url> bearer <token>
+       feedauth <url> cookie <cookie>
+       feedauth <url> header <name> <value>
+       feedauth <url> list
+       feedauth <url> clear [kind]`
+
+// middleware auth handles user
+func HandlerFeedAuth(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s managing feed credentials: %d args", user.Name, len(cmd.Args))
+	if len(cmd.Args) < 2 {
+		s.LogError("Incorrect number of arguments passed to feedauth")
+		return errors.New(feedAuthUsage)
+	}
+
+	ctx := s.Ctx
+	feedUrl := cmd.Args[0]
+	feed, err := s.Db.GetFeedByUrl(ctx, feedUrl)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.LogError("No feeds registered for url: %s", feedUrl)
+			return fmt.Errorf("no feed has that url, %v", feedUrl)
+		}
+		s.LogError("Error while retrieving feed registered with url: %v", err)
+		return err
+	}
+
+	// Only the user who added the feed may manage its credentials
+	if feed.UserID != user.ID {
+		s.LogError("User %s tried to manage credentials of feed %s owned by %s", user.Name, feed.Name, feed.User)
+		return fmt.Errorf("only %s, who added the feed, can manage its credentials", feed.User)
+	}
+
+	action := cmd.Args[1]
+	args := cmd.Args[2:]
+	switch action {
+	case "list":
+		return listFeedAuth(ctx, s, feed.ID, feed.Name)
+	case "clear":
+		return clearFeedAuth(ctx, s, feed.ID, feed.Name, args)
+	case feedAuthBasic:
+		if len(args) != 2 {
+			return errors.New(feedAuthUsage)
+		}
+		return setFeedAuth(ctx, s, feed.ID, feedAuthBasic, args[0], args[1])
+	case feedAuthBearer, feedAuthCookie:
+		if len(args) != 1 {
+			return errors.New(feedAuthUsage)
+		}
+		return setFeedAuth(ctx, s, feed.ID, action, "", args[0])
+	case feedAuthHeader:
+		if len(args) != 2 {
+			return errors.New(feedAuthUsage)
+		}
+		// These have kinds of their own, which replace each other properly
+		name := http.CanonicalHeaderKey(args[0])
+		if name == "Authorization" || name == "Cookie" {
+			return fmt.Errorf("use feedauth basic, bearer or cookie to set the %s header", name)
+		}
+		return setFeedAuth(ctx, s, feed.ID, feedAuthHeader, name, args[1])
+	default:
+		s.LogError("Unknown feedauth action: %s", action)
+		return errors.New(feedAuthUsage)
+	}
+}
+
+func setFeedAuth(ctx context.Context, s *config.State, feedId uuid.UUID, kind string, name string, secret string) error {
+	encrypted, err := s.Config.EncryptSecret(secret)
+	if err != nil {
+		s.LogError("Failed to encrypt credentials: %v", err)
+		return err
+	}
+
+	// The old credentials are only replaced once the new ones are stored
+	err = s.Db.InTx(ctx, func(q database.Querier) error {
+		for _, replaced := range replacedFeedAuthKinds(kind) {
+			err := q.DeleteFeedAuthKind(ctx, database.DeleteFeedAuthKindParams{
+				FeedID: feedId,
+				Kind:   replaced,
+			})
+			if err != nil {
+				s.LogError("Failed to replace existing %s credentials: %v", replaced, err)
+				return err
+			}
+		}
+
+		params := database.SetFeedAuthParams{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			FeedID:    feedId,
+			Kind:      kind,
+			Name:      name,
+			Value:     encrypted,
+		}
+		_, err := q.SetFeedAuth(ctx, params)
+		if err != nil {
+			s.LogError("Failed to store %s credentials: %v", kind, err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.LogInfo("Stored %s credentials for feed", kind)
+	return nil
+}
+
+// replacedFeedAuthKinds returns the kinds of credentials that new credentials of kind replace. A feed
+// only has a single set of basic, bearer or cookie credentials, and as basic and bearer credentials
+// both go in the Authorization header it only has one of those two
+func replacedFeedAuthKinds(kind string) []string {
+	switch kind {
+	case feedAuthBasic, feedAuthBearer:
+		return []string{feedAuthBasic, feedAuthBearer}
+	case feedAuthCookie:
+		return []string{feedAuthCookie}
+	default:
+		return nil
+	}
+}
+
+func listFeedAuth(ctx context.Context, s *config.State, feedId uuid.UUID, feedName string) error {
+	credentials, err := s.Db.GetFeedAuth(ctx, feedId)
+	if err != nil {
+		s.LogError("Failed to retrieve credentials: %v", err)
+		return err
+	}
+
+	if len(credentials) == 0 {
+		s.LogInfo("No credentials stored for %s", feedName)
+		return nil
+	}
+
+	// Never print the secrets themselves
+	for _, credential := range credentials {
+		name := credential.Name
+		if name == "" {
+			name = "-"
+		}
+		s.LogInfo("%-8s %-25s updated: %s", credential.Kind, name, credential.UpdatedAt.Format("Jan 02, 2006 at 15:04"))
+	}
+
+	return nil
+}
+
+func clearFeedAuth(ctx context.Context, s *config.State, feedId uuid.UUID, feedName string, args []string) error {
+	if len(args) > 0 {
+		err := s.Db.DeleteFeedAuthKind(ctx, database.DeleteFeedAuthKindParams{
+			FeedID: feedId,
+			Kind:   args[0],
+		})
+		if err != nil {
+			s.LogError("Failed to clear %s credentials: %v", args[0], err)
+			return err
+		}
+		s.LogInfo("Cleared %s credentials for %s", args[0], feedName)
+		return nil
+	}
+
+	err := s.Db.ClearFeedAuth(ctx, feedId)
+	if err != nil {
+		s.LogError("Failed to clear credentials: %v", err)
+		return err
+	}
+
+	s.LogInfo("Cleared all credentials for %s", feedName)
+	return nil
+}
+
+// feedAuthHeaders decrypts the stored credentials of a feed into the request headers they stand for
+func feedAuthHeaders(ctx context.Context, s *config.State, feedId uuid.UUID) (map[string]string, error) {
+	credentials, err := s.Db.GetFeedAuth(ctx, feedId)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving feed credentials: %v", err)
+	}
+
+	headers := make(map[string]string)
+	for _, credential := range credentials {
+		secret, err := s.Config.DecryptSecret(credential.Value)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting %s credentials: %v", credential.Kind, err)
+		}
+
+		switch credential.Kind {
+		case feedAuthBasic:
+			token := base64.StdEncoding.EncodeToString([]byte(credential.Name + ":" + secret))
+			headers["Authorization"] = "Basic " + token
+		case feedAuthBearer:
+			headers["Authorization"] = "Bearer " + secret
+		case feedAuthCookie:
+			headers["Cookie"] = secret
+		case feedAuthHeader:
+			headers[credential.Name] = secret
+		}
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/git-cst/bootdev_gator/internal/database/databasetest"
+)
+
+// failingFeedAuth is a database where storing credentials always fails
+type failingFeedAuth struct {
+	database.Querier
+	store database.Store
+}
+
+func (f failingFeedAuth) SetFeedAuth(ctx context.Context, arg database.SetFeedAuthParams) (database.FeedAuth, error) {
+	return database.FeedAuth{}, errors.New("store failed")
+}
+
+// InTx hands fn the transaction with storing credentials failing in it too
+func (f failingFeedAuth) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	return f.store.InTx(ctx, func(tx database.Querier) error { return fn(failingFeedAuth{Querier: tx}) })
+}
+
+func newFeedAuthState(t *testing.T) (*config.State, *databasetest.DB) {
+	t.Helper()
+
+	s, db := newTestState(t)
+	s.Config.SecretKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	return s, db
+}
+
+// feedAuthHeadersFor returns the headers the stored credentials of feed send
+func feedAuthHeadersFor(t *testing.T, s *config.State, feed database.Feed) map[string]string {
+	t.Helper()
+
+	headers, err := feedAuthHeaders(s.Ctx, s, feed.ID)
+	if err != nil {
+		t.Fatalf("could not read the credentials of %s: %v", feed.Name, err)
+	}
+	return headers
+}
+
+func TestFeedAuthReplacesCredentials(t *testing.T) {
+	s, _ := newFeedAuthState(t)
+	user := addUser(t, s, "bob")
+	server := serveFeed(t)
+	feed := addTestFeed(t, s, user, server)
+
+	for _, token := range []string{"first", "second"} {
+		if err := HandlerFeedAuth(s, commands.Command{Name: "feedauth", Args: []string{feed.Url, "bearer", token}}, user); err != nil {
+			t.Fatalf("feedauth failed: %v", err)
+		}
+	}
+
+	credentials, _ := s.Db.GetFeedAuth(s.Ctx, feed.ID)
+	if len(credentials) != 1 {
+		t.Fatalf("a feed has a single bearer token, got %d", len(credentials))
+	}
+	if got := feedAuthHeadersFor(t, s, feed)["Authorization"]; got != "Bearer second" {
+		t.Errorf("expected the latest token, got %q", got)
+	}
+}
+
+func TestFeedAuthKeepsCredentialsWhenStoringFails(t *testing.T) {
+	s, db := newFeedAuthState(t)
+	user := addUser(t, s, "bob")
+	server := serveFeed(t)
+	feed := addTestFeed(t, s, user, server)
+	if err := HandlerFeedAuth(s, commands.Command{Name: "feedauth", Args: []string{feed.Url, "bearer", "old"}}, user); err != nil {
+		t.Fatalf("feedauth failed: %v", err)
+	}
+
+	s.Db = failingFeedAuth{Querier: db, store: db}
+	if err := HandlerFeedAuth(s, commands.Command{Name: "feedauth", Args: []string{feed.Url, "bearer", "new"}}, user); err == nil {
+		t.Fatal("feedauth should fail when the credentials can't be stored")
+	}
+
+	if got := feedAuthHeadersFor(t, s, feed)["Authorization"]; got != "Bearer old" {
+		t.Errorf("the old token should be kept, got %q", got)
+	}
+}
+
+func TestFeedAuthOnlyByOwner(t *testing.T) {
+	s, _ := newFeedAuthState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	server := serveFeed(t)
+	feed := addTestFeed(t, s, bob, server)
+
+	for _, args := range [][]string{{feed.Url, "bearer", "token"}, {feed.Url, "list"}, {feed.Url, "clear"}} {
+		if err := HandlerFeedAuth(s, commands.Command{Name: "feedauth", Args: args}, alice); err == nil {
+			t.Errorf("feedauth %v should be refused to someone who didn't add the feed", args[1:])
+		}
+	}
+	if headers := feedAuthHeadersFor(t, s, feed); len(headers) != 0 {
+		t.Errorf("nothing should be stored, got %v", headers)
+	}
+}
+
+func TestFeedAuthBasicAndBearerReplaceEachOther(t *testing.T) {
+	s, _ := newFeedAuthState(t)
+	user := addUser(t, s, "bob")
+	feed := addTestFeed(t, s, user, serveFeed(t))
+	feedAuth := func(args ...string) error {
+		return HandlerFeedAuth(s, commands.Command{Name: "feedauth", Args: append([]string{feed.Url}, args...)}, user)
+	}
+
+	if err := feedAuth("bearer", "token"); err != nil {
+		t.Fatalf("feedauth bearer failed: %v", err)
+	}
+	if err := feedAuth("basic", "bob", "secret"); err != nil {
+		t.Fatalf("feedauth basic failed: %v", err)
+	}
+	credentials, _ := s.Db.GetFeedAuth(s.Ctx, feed.ID)
+	if len(credentials) != 1 || credentials[0].Kind != "basic" {
+		t.Fatalf("basic credentials should replace the bearer token, got %+v", credentials)
+	}
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("bob:secret"))
+	if got := feedAuthHeadersFor(t, s, feed)["Authorization"]; got != basic {
+		t.Errorf("expected %q, got %q", basic, got)
+	}
+
+	if err := feedAuth("bearer", "other"); err != nil {
+		t.Fatalf("feedauth bearer failed: %v", err)
+	}
+	if got := feedAuthHeadersFor(t, s, feed)["Authorization"]; got != "Bearer other" {
+		t.Errorf("the bearer token should replace the basic credentials, got %q", got)
+	}
+
+	for _, name := range []string{"authorization", "Cookie"} {
+		if err := feedAuth("header", name, "value"); err == nil {
+			t.Errorf("feedauth header %s should be refused", name)
+		}
+	}
+}
+
+func TestFetchFeedKeepsCredentialsOnItsHost(t *testing.T) {
+	s, _ := newTestState(t)
+
+	var received http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Write([]byte(rssBody()))
+	}))
+	t.Cleanup(other.Close)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/feed", http.StatusMovedPermanently)
+		case "/elsewhere":
+			http.Redirect(w, r, other.URL+"/feed", http.StatusFound)
+		default:
+			received = r.Header.Clone()
+			w.Write([]byte(rssBody()))
+		}
+	}))
+	t.Cleanup(origin.Close)
+
+	headers := map[string]string{"X-Api-Key": "secret", "Authorization": "Bearer token"}
+	if _, err := fetchFeed(s.Config, s.Ctx, origin.URL+"/moved", headers); err != nil {
+		t.Fatalf("fetchFeed failed: %v", err)
+	}
+	if received.Get("X-Api-Key") != "secret" || received.Get("Authorization") != "Bearer token" {
+		t.Errorf("a redirect on the same host should keep the credentials, got %v", received)
+	}
+
+	if _, err := fetchFeed(s.Config, s.Ctx, origin.URL+"/elsewhere", headers); err != nil {
+		t.Fatalf("fetchFeed failed: %v", err)
+	}
+	if received.Get("X-Api-Key") != "" || received.Get("Authorization") != "" {
+		t.Errorf("credentials shouldn't be sent to another host, got %v", received)
+	}
+}
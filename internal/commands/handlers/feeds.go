@@ -16,6 +16,28 @@ import (
 // middleware auth handles user
 func HandlerAddFeed(s *config.State, cmd commands.Command, user database.User) error {
 	s.LogDebug("User %s attempting to add feed: args=%v", user.Name, cmd.Args)
+
+	// --first-fetch <policy> overrides the configured first fetch policy for a new feed
+	var firstFetch sql.NullString
+	var args []string
+	for i := 0; i < len(cmd.Args); i++ {
+		if cmd.Args[i] != "--first-fetch" {
+			args = append(args, cmd.Args[i])
+			continue
+		}
+		if i+1 >= len(cmd.Args) {
+			return fmt.Errorf("--first-fetch expects a policy: all, mark_read, latest:<items> or days:<days>")
+		}
+		i++
+		policy, err := config.ParseFirstFetchPolicy(cmd.Args[i])
+		if err != nil {
+			s.LogError("Failed to add feed: %v", err)
+			return err
+		}
+		firstFetch = sql.NullString{String: policy.String(), Valid: true}
+	}
+	cmd.Args = args
+
 	if len(cmd.Args) < 2 {
 		errMsg := fmt.Sprintf("incorrect number of arguments passed, expected 2 (name of feed & url): %v", cmd.Args)
 		s.LogError("Failed to add feed: %s", errMsg)
@@ -30,44 +52,58 @@ func HandlerAddFeed(s *config.State, cmd commands.Command, user database.User) e
 		return fmt.Errorf("second argument should be the url, the passed argument does not fulfill url schema: %v", cmd.Args[1])
 	}
 
-	ctx := context.Background()
-	// Check if feed already exists
+	ctx := s.Ctx
+	// Creating the feed and following it happen together, so a failed follow can't leave an orphaned feed
 	var feedId uuid.UUID
-	feed, err := s.Db.GetFeedByUrl(ctx, feedUrl)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			// Feed doesn't exist so create the feed
-			s.LogInfo("Creating new feed '%s' with URL '%s'", feedName, feedUrl)
-			params := database.CreateFeedParams{
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-				Name:      feedName,
-				Url:       feedUrl,
-				UserID:    user.ID,
-			}
-			createdFeed, err := s.Db.CreateFeed(ctx, params)
-			if err != nil {
-				s.LogError("Failed to create feed in database: %v", err)
+	err := s.Db.InTx(ctx, func(q database.Querier) error {
+		// Check if feed already exists
+		feed, err := q.GetFeedByUrl(ctx, feedUrl)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// Feed doesn't exist so create the feed
+				s.LogInfo("Creating new feed '%s' with URL '%s'", feedName, feedUrl)
+				params := database.CreateFeedParams{
+					CreatedAt:  time.Now(),
+					UpdatedAt:  time.Now(),
+					Name:       feedName,
+					Url:        feedUrl,
+					UserID:     user.ID,
+					FirstFetch: firstFetch,
+				}
+				createdFeed, err := q.CreateFeed(ctx, params)
+				if err != nil {
+					s.LogError("Failed to create feed in database: %v", err)
+					return err
+				}
+				feedId = createdFeed.ID
+				s.LogInfo("Successfully created feed: id=%s, name=%s", createdFeed.ID, feedName)
+			} else {
+				// Handle other errors
+				s.LogError("Error checking for existing feed: %v", err)
 				return err
 			}
-			feedId = createdFeed.ID
-			s.LogInfo("Successfully created feed: id=%s, name=%s", createdFeed.ID, feedName)
 		} else {
-			// Handle other errors
-			s.LogError("Error checking for existing feed: %v", err)
-			return err
+			feedId = feed.ID
+			s.LogInfo("Feed already exists, using existing feed: id=%s, name=%s", feed.ID, feed.Name)
+			if firstFetch.Valid {
+				s.LogInfo("Ignoring --first-fetch, the feed was added before")
+			}
 		}
-	} else {
-		feedId = feed.ID
-		s.LogInfo("Feed already exists, using existing feed: id=%s, name=%s", feed.ID, feed.Name)
-	}
 
-	err = followFeed(ctx, s, feedId, user)
+		err = followFeed(ctx, q, feedId, user)
+		if err != nil {
+			s.LogError("Failed to follow feed: %v", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		s.LogError("Failed to follow feed: %v", err)
 		return err
 	}
 
+	// Let running aggregators know so they can fetch the feed straight away
+	notify(ctx, s, feedAddedChannel, feedId.String())
+
 	s.LogDebug("User %s successfully added and followed feed: id=%s, name=%s", user.Name, feedId, feedName)
 	return nil
 }
@@ -87,7 +123,7 @@ func HandlerFollowFeed(s *config.State, cmd commands.Command, user database.User
 		return fmt.Errorf("no url passed to the follow feed handler: %v", cmd.Args)
 	}
 
-	ctx := context.Background()
+	ctx := s.Ctx
 	feedUrl := cmd.Args[0]
 	feed, err := s.Db.GetFeedByUrl(ctx, feedUrl)
 	if err != nil {
@@ -101,12 +137,15 @@ func HandlerFollowFeed(s *config.State, cmd commands.Command, user database.User
 		return err
 	}
 
-	err = followFeed(ctx, s, feed.ID, user)
+	err = followFeed(ctx, s.Db, feed.ID, user)
 	if err != nil {
 		s.LogError("Failed to follow feed: %v", err)
 		return err
 	}
 
+	// Let running aggregators know in case the feed was never fetched
+	notify(ctx, s, feedAddedChannel, feed.ID.String())
+
 	s.LogDebug("User %s successfully followed feed: id=%s, name=%s", user.Name, feed.ID, feed.Name)
 	return nil
 }
@@ -114,7 +153,7 @@ func HandlerFollowFeed(s *config.State, cmd commands.Command, user database.User
 func HandlerGetFeeds(s *config.State, cmd commands.Command) error {
 	s.LogDebug("Getting all feeds")
 
-	ctx := context.Background()
+	ctx := s.Ctx
 	feedData, err := s.Db.GetFeeds(ctx)
 	if err != nil {
 		// Check if this is a no data found error
@@ -142,7 +181,7 @@ func HandlerGetFeeds(s *config.State, cmd commands.Command) error {
 // middleware auth handles user
 func HandlerGetFollowing(s *config.State, cmd commands.Command, user database.User) error {
 	s.LogDebug("Getting feeds that %s (%v) is following", user.Name, user.ID)
-	ctx := context.Background()
+	ctx := s.Ctx
 
 	feedFollows, err := s.Db.GetFeedFollowsForUser(ctx, user.ID)
 	if err != nil {
@@ -156,8 +195,45 @@ func HandlerGetFollowing(s *config.State, cmd commands.Command, user database.Us
 		return err
 	}
 
-	for _, follow := range feedFollows {
-		s.LogInfo("%s is following: %s", user.Name, follow.FeedName)
+	tags, err := s.Db.GetFollowTagsForUser(ctx, user.ID)
+	if err != nil {
+		s.LogError("Could not retrieve the tags of %s's follows: %v", user.Name, err)
+		return err
+	}
+
+	// Without any tags the follows are listed as they are, otherwise grouped by folder (tag) with
+	// untagged follows last. A follow with several tags shows up in each of its folders
+	if len(tags) == 0 {
+		for _, follow := range feedFollows {
+			s.LogInfo("%s is following: %s (%d unread)", user.Name, follow.FeedName, follow.Unread)
+		}
+	} else {
+		tagged := map[uuid.UUID]bool{}
+		for i, tag := range tags {
+			if i == 0 || tags[i-1].Tag != tag.Tag {
+				s.LogInfo(ColorGreen+"%s:"+ColorReset, tag.Tag)
+			}
+			tagged[tag.FeedID] = true
+			for _, follow := range feedFollows {
+				if follow.FeedID == tag.FeedID {
+					s.LogInfo("    %s (%d unread)", follow.FeedName, follow.Unread)
+					break
+				}
+			}
+		}
+
+		var untagged []database.GetFeedFollowsForUserRow
+		for _, follow := range feedFollows {
+			if !tagged[follow.FeedID] {
+				untagged = append(untagged, follow)
+			}
+		}
+		if len(untagged) > 0 {
+			s.LogInfo(ColorGreen + "untagged:" + ColorReset)
+			for _, follow := range untagged {
+				s.LogInfo("    %s (%d unread)", follow.FeedName, follow.Unread)
+			}
+		}
 	}
 
 	s.LogDebug("Successfully retrieved follows for %s (%v) user", user.Name, user.ID)
@@ -178,7 +254,7 @@ func HandlerUnfollow(s *config.State, cmd commands.Command, user database.User)
 	}
 
 	s.LogDebug("Unfollowing feed with url: %s", cmd.Args[0])
-	ctx := context.Background()
+	ctx := s.Ctx
 	feedUrl := cmd.Args[0]
 	feed, err := s.Db.GetFeedByUrl(ctx, feedUrl)
 	if err != nil {
@@ -205,3 +281,118 @@ func HandlerUnfollow(s *config.State, cmd commands.Command, user database.User)
 	s.LogInfo("Successfully unfollowed feed with url: %s", feedUrl)
 	return nil
 }
+
+// middleware auth handles user
+func HandlerRemoveFeed(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s removing a feed: args=%v", user.Name, cmd.Args)
+
+	// --force removes the feed even though other users still follow it
+	force := false
+	feedUrl := ""
+	for _, arg := range cmd.Args {
+		if arg == "--force" {
+			force = true
+			continue
+		}
+		if feedUrl != "" {
+			return fmt.Errorf("removefeed expects one feed url: %v", cmd.Args)
+		}
+		feedUrl = arg
+	}
+	if feedUrl == "" {
+		return fmt.Errorf("removefeed expects the url of the feed to remove: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	// The hub is told to stop pushing the feed before it's deleted, the callback confirms it while
+	// the subscription is marked unsubscribing and after it's gone
+	var subscription database.WebsubSubscription
+	subscribed := false
+	err := s.Db.InTx(ctx, func(q database.Querier) error {
+		feed, err := removableFeed(ctx, s, q, user, feedUrl, force)
+		if err != nil {
+			return err
+		}
+
+		subscription, err = q.GetWebSubSubscriptionByFeed(ctx, feed.ID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		subscribed = true
+		return q.SetWebSubSubscriptionState(ctx, database.SetWebSubSubscriptionStateParams{
+			UpdatedAt: time.Now(),
+			State:     webSubUnsubscribing,
+			ID:        subscription.ID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if subscribed {
+		s.LogInfo("Unsubscribing from WebSub hub %s", subscription.HubUrl)
+		// The hub stops getting confirmations once the subscription is gone, so it's removed regardless
+		if err := requestWebSubUnsubscription(ctx, s, subscription); err != nil {
+			s.LogError("Failed to unsubscribe from WebSub hub %s: %v", subscription.HubUrl, err)
+		}
+	}
+
+	var feedName string
+	err = s.Db.InTx(ctx, func(q database.Querier) error {
+		feed, err := removableFeed(ctx, s, q, user, feedUrl, force)
+		if err != nil {
+			return err
+		}
+		feedName = feed.Name
+
+		// Posts, follows, fetch history, credentials and the WebSub subscription of the feed are deleted with it
+		err = q.DeleteFeed(ctx, feed.ID)
+		if err != nil {
+			s.LogError("Could not remove feed %s: %v", feed.Name, err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.LogInfo("Removed feed %s (%s) and its posts", feedName, feedUrl)
+	return nil
+}
+
+// removableFeed looks up the feed with feedUrl, failing unless user may remove it
+func removableFeed(ctx context.Context, s *config.State, q database.Querier, user database.User, feedUrl string, force bool) (database.GetFeedByUrlRow, error) {
+	feed, err := q.GetFeedByUrl(ctx, feedUrl)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.LogError("No feed registered with url: %s", feedUrl)
+			return feed, fmt.Errorf("no feed has that url, %v", feedUrl)
+		}
+		s.LogError("Error while retrieving feed registered with url: %v", err)
+		return feed, err
+	}
+
+	// Only the user who added the feed, or an admin, may remove it
+	if feed.UserID != user.ID && !user.IsAdmin {
+		s.LogError("User %s tried to remove feed %s added by %s", user.Name, feed.Name, feed.User)
+		return feed, fmt.Errorf("only %s, who added the feed, or an admin can remove it", feed.User)
+	}
+
+	followers, err := q.CountOtherFollowers(ctx, database.CountOtherFollowersParams{
+		FeedID: feed.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		s.LogError("Could not count the followers of %s: %v", feed.Name, err)
+		return feed, err
+	}
+	if followers > 0 && !force {
+		s.LogError("Not removing feed %s, %d other users follow it", feed.Name, followers)
+		return feed, fmt.Errorf("%d other users follow %s, pass --force to remove it anyway", followers, feed.Name)
+	}
+	return feed, nil
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// failingFollows is a database where following a feed always fails
+type failingFollows struct {
+	database.Querier
+	store database.Store
+}
+
+func (f failingFollows) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	return database.CreateFeedFollowRow{}, errors.New("follow failed")
+}
+
+// InTx hands fn the transaction with following failing in it too
+func (f failingFollows) InTx(ctx context.Context, fn func(database.Querier) error) error {
+	return f.store.InTx(ctx, func(tx database.Querier) error { return fn(failingFollows{Querier: tx}) })
+}
+
+func TestAddFeedCreatesAndFollows(t *testing.T) {
+	s, db := newTestState(t)
+	user := addUser(t, s, "bob")
+
+	err := HandlerAddFeed(s, commands.Command{Name: "addfeed", Args: []string{"Blog", "https://example.com/rss", "--first-fetch", "latest:2"}}, user)
+	if err != nil {
+		t.Fatalf("addfeed failed: %v", err)
+	}
+
+	feed := getFeed(t, s, "https://example.com/rss")
+	if feed.Name != "Blog" || feed.UserID != user.ID {
+		t.Errorf("feed was stored as %+v", feed)
+	}
+	if feed.FirstFetch.String != "latest:2" {
+		t.Errorf("expected first fetch policy latest:2, got %q", feed.FirstFetch.String)
+	}
+
+	follows, _ := s.Db.GetFeedFollowsForUser(s.Ctx, user.ID)
+	if len(follows) != 1 || follows[0].FeedID != feed.ID {
+		t.Errorf("expected bob to follow the feed, got %+v", follows)
+	}
+
+	if notifications := db.Notifications(); len(notifications) != 1 || notifications[0].Payload != feed.ID.String() {
+		t.Errorf("expected the new feed to be announced, got %+v", notifications)
+	}
+}
+
+func TestAddFeedFollowsExistingFeed(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+
+	cmd := commands.Command{Name: "addfeed", Args: []string{"Blog", "https://example.com/rss"}}
+	if err := HandlerAddFeed(s, cmd, bob); err != nil {
+		t.Fatalf("addfeed failed: %v", err)
+	}
+	if err := HandlerAddFeed(s, cmd, alice); err != nil {
+		t.Fatalf("adding an existing feed failed: %v", err)
+	}
+
+	feeds, _ := s.Db.GetAllFeeds(s.Ctx)
+	if len(feeds) != 1 || feeds[0].UserID != bob.ID {
+		t.Fatalf("expected the single feed added by bob, got %+v", feeds)
+	}
+	follows, _ := s.Db.GetFeedFollowsForUser(s.Ctx, alice.ID)
+	if len(follows) != 1 {
+		t.Errorf("expected alice to follow the existing feed, got %+v", follows)
+	}
+}
+
+func TestAddFeedRejectsInvalidArguments(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+
+	for _, args := range [][]string{
+		{"Blog"},
+		{"Blog", "not a url"},
+		{"Blog", "https://example.com/rss", "--first-fetch", "latest"},
+		{"Blog", "https://example.com/rss", "--first-fetch"},
+	} {
+		err := HandlerAddFeed(s, commands.Command{Name: "addfeed", Args: args}, user)
+		if err == nil {
+			t.Errorf("addfeed %v should have failed", args)
+		}
+	}
+
+	feeds, _ := s.Db.GetAllFeeds(s.Ctx)
+	if len(feeds) != 0 {
+		t.Errorf("expected no feeds, got %+v", feeds)
+	}
+}
+
+func TestAddFeedRollsBackWhenFollowFails(t *testing.T) {
+	s, db := newTestState(t)
+	user := addUser(t, s, "bob")
+	s.Db = failingFollows{Querier: db, store: db}
+
+	err := HandlerAddFeed(s, commands.Command{Name: "addfeed", Args: []string{"Blog", "https://example.com/rss"}}, user)
+	if err == nil {
+		t.Fatal("addfeed should fail when the follow fails")
+	}
+
+	feeds, _ := db.GetAllFeeds(s.Ctx)
+	if len(feeds) != 0 {
+		t.Errorf("the feed should have been rolled back, got %+v", feeds)
+	}
+	if notifications := db.Notifications(); len(notifications) != 0 {
+		t.Errorf("nothing should be announced for a rolled back feed, got %+v", notifications)
+	}
+}
+
+func TestRemoveFeed(t *testing.T) {
+	const url = "https://example.com/rss"
+
+	tests := []struct {
+		name string
+		// remover is who runs removefeed, bob added the feed
+		remover string
+		// aliceAdmin grants alice admin rights
+		aliceAdmin bool
+		// aliceFollows makes a second user follow the feed
+		aliceFollows bool
+		force        bool
+		removed      bool
+	}{
+		{name: "owner", remover: "bob", removed: true},
+		{name: "not the owner", remover: "alice"},
+		// The owner is whoever has the id the feed was added with, not whoever has the name
+		{name: "same name as the owner", remover: "other bob", force: true},
+		// bob still follows the feed, so an admin has to force it
+		{name: "admin", remover: "alice", aliceAdmin: true},
+		{name: "admin forced", remover: "alice", aliceAdmin: true, force: true, removed: true},
+		{name: "followed by others", remover: "bob", aliceFollows: true},
+		{name: "followed by others forced", remover: "bob", aliceFollows: true, force: true, removed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestState(t)
+			users := map[string]database.User{
+				"bob":       addUser(t, s, "bob"),
+				"alice":     addUser(t, s, "alice"),
+				"other bob": {ID: uuid.New(), Name: "bob"},
+			}
+			if tt.aliceAdmin {
+				users["alice"] = grantAdmin(t, s, "alice")
+			}
+
+			err := HandlerAddFeed(s, commands.Command{Name: "addfeed", Args: []string{"Blog", url}}, users["bob"])
+			if err != nil {
+				t.Fatalf("addfeed failed: %v", err)
+			}
+			if tt.aliceFollows {
+				err := HandlerFollowFeed(s, commands.Command{Name: "follow", Args: []string{url}}, users["alice"])
+				if err != nil {
+					t.Fatalf("follow failed: %v", err)
+				}
+			}
+			feed := getFeed(t, s, url)
+			_, err = s.Db.CreatePosts(s.Ctx, database.CreatePostsParams{
+				Now:          time.Now(),
+				FeedID:       feed.ID,
+				Titles:       []string{"First"},
+				Urls:         []string{"https://example.com/first"},
+				Descriptions: []string{""},
+				PublishedAts: []time.Time{time.Now()},
+				Contents:     []string{""},
+			})
+			if err != nil {
+				t.Fatalf("could not store a post: %v", err)
+			}
+
+			args := []string{url}
+			if tt.force {
+				args = append(args, "--force")
+			}
+			err = HandlerRemoveFeed(s, commands.Command{Name: "removefeed", Args: args}, users[tt.remover])
+
+			feeds, _ := s.Db.GetAllFeeds(s.Ctx)
+			if !tt.removed {
+				if err == nil {
+					t.Error("removefeed should have failed")
+				}
+				if len(feeds) != 1 || len(postsFor(t, s, users["bob"])) != 1 {
+					t.Errorf("the feed and its posts should be left alone, got %+v", feeds)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("removefeed failed: %v", err)
+			}
+			if len(feeds) != 0 {
+				t.Errorf("the feed should be gone, got %+v", feeds)
+			}
+			for _, user := range users {
+				follows, _ := s.Db.GetFeedFollowsForUser(s.Ctx, user.ID)
+				if len(follows) != 0 {
+					t.Errorf("%s still follows %+v", user.Name, follows)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveFeedUnknownUrl(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+
+	err := HandlerRemoveFeed(s, commands.Command{Name: "removefeed", Args: []string{"https://example.com/rss"}}, user)
+	if err == nil {
+		t.Error("removing a feed that doesn't exist should fail")
+	}
+}
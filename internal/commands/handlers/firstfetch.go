@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// firstFetchPolicy returns the feed's own first fetch policy, or the configured default when it has none
+func firstFetchPolicy(s *config.State, feed database.Feed) (config.FirstFetchPolicy, error) {
+	if feed.FirstFetch.Valid {
+		return config.ParseFirstFetchPolicy(feed.FirstFetch.String)
+	}
+	return s.Config.Agg.FirstFetchPolicy()
+}
+
+// firstFetchItems narrows down the items of a feed's first fetch so a long backlog doesn't flood browse
+func firstFetchItems(policy config.FirstFetchPolicy, items []RSSItem) []RSSItem {
+	switch policy.Mode {
+	case config.FirstFetchLatest:
+		return latestItems(items, policy.Limit)
+	case config.FirstFetchDays:
+		return itemsSince(items, time.Now().Add(-policy.MaxAge()))
+	default:
+		return items
+	}
+}
+
+// latestItems returns the limit most recently published items
+func latestItems(items []RSSItem, limit int) []RSSItem {
+	if len(items) <= limit {
+		return items
+	}
+
+	// Feeds aren't always ordered newest first
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b RSSItem) int {
+		return b.publishedAt().Compare(a.publishedAt())
+	})
+	return sorted[:limit]
+}
+
+// itemsSince returns the items published after cutoff
+func itemsSince(items []RSSItem, cutoff time.Time) []RSSItem {
+	var recent []RSSItem
+	for _, item := range items {
+		if item.publishedAt().After(cutoff) {
+			recent = append(recent, item)
+		}
+	}
+	return recent
+}
+
+// markFirstFetchRead marks everything imported on a feed's first fetch as read for the feed's followers
+func markFirstFetchRead(ctx context.Context, q database.Querier, feed database.Feed) error {
+	err := q.MarkFeedPostsReadForFollowers(ctx, database.MarkFeedPostsReadForFollowersParams{
+		FeedID: feed.ID,
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not mark the first posts of feed %s as read: %v", feed.Name, err)
+	}
+	return nil
+}
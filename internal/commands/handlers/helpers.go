@@ -39,7 +39,8 @@ func isValidURL(str string) bool {
 }
 
 // Used in feeds.go
-func followFeed(ctx context.Context, s *config.State, feedId uuid.UUID, user database.User) error {
+// followFeed takes the queries to run so it can be part of a transaction
+func followFeed(ctx context.Context, q database.Querier, feedId uuid.UUID, user database.User) error {
 	params := database.CreateFeedFollowParams{
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -47,7 +48,7 @@ func followFeed(ctx context.Context, s *config.State, feedId uuid.UUID, user dat
 		FeedID:    feedId,
 	}
 
-	follow, err := s.Db.CreateFeedFollow(ctx, params)
+	follow, err := q.CreateFeedFollow(ctx, params)
 	if err != nil {
 		return fmt.Errorf("error whilst creating feed follow: %v", err)
 	}
@@ -56,6 +57,34 @@ func followFeed(ctx context.Context, s *config.State, feedId uuid.UUID, user dat
 	return nil
 }
 
+// Used in posts.go, reads.go and stars.go
+// shortIDLength is how much of a post id browse shows, usually enough to tell posts apart
+const shortIDLength = 8
+
+func shortID(id uuid.UUID) string {
+	return id.String()[:shortIDLength]
+}
+
+// findPost looks up a post of a feed the user follows, or one they starred, by its url or the start of its id
+func findPost(ctx context.Context, s *config.State, user database.User, ref string) (database.FindPostsForUserRow, error) {
+	posts, err := s.Db.FindPostsForUser(ctx, database.FindPostsForUserParams{
+		UserID: user.ID,
+		Ref:    ref,
+	})
+	if err != nil {
+		return database.FindPostsForUserRow{}, err
+	}
+
+	switch len(posts) {
+	case 0:
+		return database.FindPostsForUserRow{}, fmt.Errorf("no post of the feeds you follow or your starred posts has the url or id %q", ref)
+	case 1:
+		return posts[0], nil
+	default:
+		return database.FindPostsForUserRow{}, fmt.Errorf("several posts have an id starting with %q, pass more of the id or the url", ref)
+	}
+}
+
 func parseTimeString(timeString string) (time.Time, error) {
 	// Try several common RSS date formats
 	formats := []string{
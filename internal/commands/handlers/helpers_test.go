@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/git-cst/bootdev_gator/internal/database/databasetest"
+)
+
+// newTestState returns a state backed by an empty in-memory SQLite database, logging nowhere
+func newTestState(t *testing.T) (*config.State, *databasetest.DB) {
+	t.Helper()
+
+	db := databasetest.New(t)
+	s := &config.State{
+		Config: &config.Config{},
+		Db:     db,
+		Logger: &config.LogInstance{Log: log.New(io.Discard, "", 0)},
+		Ctx:    context.Background(),
+	}
+	s.Config.Client = config.NewClient(config.ClientOptions{Timeout: 5 * time.Second})
+	return s, db
+}
+
+// addUser registers a user directly in the database
+func addUser(t *testing.T, s *config.State, name string) database.User {
+	t.Helper()
+
+	user, err := s.Db.CreateUser(s.Ctx, database.CreateUserParams{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+	})
+	if err != nil {
+		t.Fatalf("could not create user %s: %v", name, err)
+	}
+	return user
+}
+
+// grantAdmin makes the user with name an admin and returns the user as the middleware would load it
+func grantAdmin(t *testing.T, s *config.State, name string) database.User {
+	t.Helper()
+
+	_, err := s.Db.SetUserAdmin(s.Ctx, database.SetUserAdminParams{IsAdmin: true, UpdatedAt: time.Now(), Name: name})
+	if err != nil {
+		t.Fatalf("could not make %s an admin: %v", name, err)
+	}
+	user, err := s.Db.GetUser(s.Ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// getFeed looks up a feed by url, failing the test when it doesn't exist
+func getFeed(t *testing.T, s *config.State, url string) database.Feed {
+	t.Helper()
+
+	feeds, err := s.Db.GetFeedsByUrlOrName(s.Ctx, url)
+	if err != nil || len(feeds) != 1 {
+		t.Fatalf("expected one feed with url %s, got %d (%v)", url, len(feeds), err)
+	}
+	return feeds[0]
+}
+
+// postsFor returns the titles of every post the user sees in browse, newest first
+func postsFor(t *testing.T, s *config.State, user database.User) []string {
+	t.Helper()
+
+	posts, err := s.Db.GetPostsForUser(s.Ctx, database.GetPostsForUserParams{
+		UserID:      user.ID,
+		ResultLimit: 100,
+	})
+	if err != nil {
+		t.Fatalf("could not get posts of %s: %v", user.Name, err)
+	}
+
+	var titles []string
+	for _, post := range posts {
+		titles = append(titles, post.Title)
+	}
+	return titles
+}
+
+// browsedTitle matches the title of a post in the output of browse and search
+var browsedTitle = regexp.MustCompile(`Title:` + regexp.QuoteMeta(ColorReset) + ` (.*?) \| `)
+
+// logged runs handler and returns what it logged
+func logged(t *testing.T, s *config.State, handler func() error) (string, error) {
+	t.Helper()
+
+	var out strings.Builder
+	logger := s.Logger
+	s.Logger = &config.LogInstance{Log: log.New(&out, "", 0)}
+	defer func() { s.Logger = logger }()
+
+	err := handler()
+	return out.String(), err
+}
+
+// browse runs the browse command with args and returns the titles it printed, in order
+func browse(t *testing.T, s *config.State, user database.User, args ...string) []string {
+	t.Helper()
+
+	out, err := logged(t, s, func() error {
+		return HandlerBrowse(s, commands.Command{Name: "browse", Args: args}, user)
+	})
+	if err != nil {
+		t.Fatalf("browse %v failed: %v", args, err)
+	}
+
+	var titles []string
+	for _, match := range browsedTitle.FindAllStringSubmatch(out, -1) {
+		titles = append(titles, match[1])
+	}
+	return titles
+}
+
+// testItem is an item of a feed served by serveFeed
+type testItem struct {
+	Title       string
+	Description string
+	PubDate     time.Time
+}
+
+// rssBody renders items as an RSS document, items with a zero PubDate have no date
+func rssBody(items ...testItem) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title><link>https://example.com</link>`)
+	for _, item := range items {
+		slug := strings.ReplaceAll(strings.ToLower(item.Title), " ", "-")
+		fmt.Fprintf(&b, "<item><title>%s</title><link>https://example.com/%s</link>", item.Title, slug)
+		if item.Description != "" {
+			fmt.Fprintf(&b, "<description>%s</description>", item.Description)
+		}
+		if !item.PubDate.IsZero() {
+			fmt.Fprintf(&b, "<pubDate>%s</pubDate>", item.PubDate.Format(time.RFC1123Z))
+		}
+		b.WriteString("</item>")
+	}
+	b.WriteString("</channel></rss>")
+	return b.String()
+}
+
+// feedServer serves whatever body, status and headers are set, so tests can change the feed between fetches
+type feedServer struct {
+	*httptest.Server
+	Body   string
+	Status int
+	Header http.Header
+	// Requests counts the requests served so far
+	Requests atomic.Int32
+}
+
+func serveFeed(t *testing.T, items ...testItem) *feedServer {
+	t.Helper()
+
+	server := &feedServer{Body: rssBody(items...), Status: http.StatusOK, Header: http.Header{}}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.Requests.Add(1)
+		for key, values := range server.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(server.Status)
+		io.WriteString(w, server.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
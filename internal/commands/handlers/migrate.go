@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/git-cst/bootdev_gator/sql/migrations"
+	"github.com/pressly/goose/v3"
+)
+
+func HandlerMigrate(s *config.State, cmd commands.Command) error {
+	s.LogDebug("Migrating database: args=%v", cmd.Args)
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("migrate expects up [version], down [version] or status: %v", cmd.Args)
+	}
+
+	// up and down optionally take the version to migrate to
+	var version int64
+	if len(cmd.Args) > 1 {
+		parsed, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version %q", cmd.Args[1])
+		}
+		version = parsed
+	}
+
+	provider, err := newMigrationProvider(s)
+	if err != nil {
+		s.LogError("Could not load migrations: %v", err)
+		return err
+	}
+
+	ctx := s.Ctx
+	var results []*goose.MigrationResult
+	switch cmd.Args[0] {
+	case "up":
+		if version > 0 {
+			results, err = provider.UpTo(ctx, version)
+		} else {
+			results, err = provider.Up(ctx)
+		}
+	case "down":
+		// Without a version only the latest migration is rolled back
+		if len(cmd.Args) > 1 {
+			results, err = provider.DownTo(ctx, version)
+		} else {
+			var result *goose.MigrationResult
+			result, err = provider.Down(ctx)
+			if result != nil {
+				results = append(results, result)
+			}
+		}
+	case "status":
+		return migrationStatus(s, provider)
+	default:
+		return fmt.Errorf("unknown migrate action %q, expected up, down or status", cmd.Args[0])
+	}
+
+	for _, result := range results {
+		s.LogInfo(ColorGreen+"OK"+ColorReset+" %s %s (%v)", result.Direction, path.Base(result.Source.Path), result.Duration.Round(time.Millisecond))
+	}
+	if err != nil {
+		s.LogError("Migration failed: %v", err)
+		return err
+	}
+
+	if len(results) == 0 {
+		s.LogInfo("Nothing to migrate")
+	}
+
+	current, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+	s.LogInfo("Database is at version %d", current)
+	return nil
+}
+
+// newMigrationProvider loads the migrations matching the configured database
+func newMigrationProvider(s *config.State) (*goose.Provider, error) {
+	driver, _, err := database.Driver(s.Config.DbURL)
+	if err != nil {
+		return nil, err
+	}
+	return migrations.NewProvider(driver, s.DBConn)
+}
+
+func migrationStatus(s *config.State, provider *goose.Provider) error {
+	statuses, err := provider.Status(s.Ctx)
+	if err != nil {
+		s.LogError("Could not retrieve migration status: %v", err)
+		return err
+	}
+
+	s.LogInfo("%-40s %s", "Migration", "Applied at")
+	for _, status := range statuses {
+		appliedAt := ColorRed + "pending" + ColorReset
+		if status.State == goose.StateApplied {
+			appliedAt = status.AppliedAt.Format("Jan 02, 2006 at 15:04")
+		}
+		s.LogInfo("%-40s %s", path.Base(status.Source.Path), appliedAt)
+	}
+
+	return nil
+}
+
+// CheckSchemaVersion makes sure the database schema matches the migrations built into this binary.
+// Pending migrations are applied when auto_migrate is set, otherwise the user is told what to run
+func CheckSchemaVersion(s *config.State) error {
+	provider, err := newMigrationProvider(s)
+	if err != nil {
+		return err
+	}
+
+	ctx := s.Ctx
+	current, target, err := provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read the database schema version: %v", err)
+	}
+
+	switch {
+	case current == target:
+		return nil
+	case current > target:
+		// Rolling back could lose data, leave it to the user
+		s.LogError("The database schema (version %d) is newer than this version of gator expects (%d), consider upgrading gator", current, target)
+		return nil
+	case s.Config.AutoMigrate:
+		s.LogInfo("Migrating database schema from version %d to %d", current, target)
+		_, err := provider.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("automatic migration failed: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("the database schema is at version %d but gator needs version %d, run `gator migrate up` (or set auto_migrate in the config)", current, target)
+	}
+}
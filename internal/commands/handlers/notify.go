@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Postgres channels used to tell running processes about changes
+const (
+	feedAddedChannel  = "gator_feed_added"
+	postsAddedChannel = "gator_posts_added"
+)
+
+// notify sends a Postgres NOTIFY, failures are logged as listeners fall back to polling anyway
+func notify(ctx context.Context, s *config.State, channel string, payload string) {
+	err := s.Db.Notify(ctx, database.NotifyParams{
+		Channel: channel,
+		Payload: payload,
+	})
+	if err != nil {
+		s.LogError("Could not notify %s: %v", channel, err)
+	}
+}
+
+// listen opens a dedicated connection listening on channel. It returns a nil listener when that isn't
+// possible, receiving from the resulting nil channel blocks forever so callers can select on it regardless
+func listen(s *config.State, channel string) (*pq.Listener, <-chan *pq.Notification) {
+	// Only Postgres has notifications
+	if driver, _, _ := database.Driver(s.Config.DbURL); driver != database.DriverPostgres {
+		s.LogDebug("No notifications on %s with %s, polling instead", channel, driver)
+		return nil, nil
+	}
+
+	listener := pq.NewListener(s.Config.DbURL, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			s.LogError("Listener on %s: %v", channel, err)
+		}
+	})
+
+	err := listener.Listen(channel)
+	if err != nil {
+		s.LogError("Could not listen on %s, falling back to polling: %v", channel, err)
+		listener.Close()
+		return nil, nil
+	}
+
+	s.LogDebug("Listening for notifications on %s", channel)
+	return listener, listener.Notify
+}
+
+// fetchAddedFeed fetches a feed announced on feedAddedChannel if nobody has fetched it successfully yet
+func fetchAddedFeed(ctx context.Context, s *config.State, notification *pq.Notification) {
+	// A nil notification is sent after the listener reconnected
+	if notification == nil {
+		return
+	}
+
+	feedId, err := uuid.Parse(notification.Extra)
+	if err != nil {
+		s.LogError("Invalid feed id in notification: %q", notification.Extra)
+		return
+	}
+
+	feed, err := s.Db.GetFeedByID(ctx, feedId)
+	if err != nil {
+		s.LogError("Could not retrieve notified feed %v: %v", feedId, err)
+		return
+	}
+
+	// Following a feed that was fetched before doesn't need an immediate fetch, one that only failed so far does
+	if feed.FirstFetchedAt.Valid {
+		s.LogDebug("Notified feed %s was already fetched", feed.Name)
+		return
+	}
+
+	s.LogInfo("New feed %s added, fetching it now", feed.Name)
+	refreshFeed(ctx, s, feed)
+}
@@ -1,59 +1,198 @@
 package handlers
 
 import (
-	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/git-cst/bootdev_gator/internal/commands"
 	"github.com/git-cst/bootdev_gator/internal/config"
 	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/google/uuid"
 )
 
 const (
-	ColorReset = "\033[0m"
-	ColorRed   = "\033[31m"
-	ColorGreen = "\033[32m"
+	ColorReset  = "\033[0m"
+	ColorRed    = "\033[31m"
+	ColorGreen  = "\033[32m"
+	ColorYellow = "\033[33m"
 )
 
 // middleware auth handles user
 func HandlerBrowse(s *config.State, cmd commands.Command, user database.User) error {
 	s.LogDebug("User %s requesting to see posts: args=%v", user.Name, cmd.Args)
-	var numPosts int32
-	if len(cmd.Args) < 1 {
-		numPosts = 2
-	} else {
-		num, err := strconv.Atoi(cmd.Args[0])
-		if err != nil {
-			s.LogError("Could not convert %v to an integer", cmd.Args[0])
+
+	// --unread leaves out the posts you have read, --folder <tag> only shows the feeds you tagged with it
+	numPosts := int32(2)
+	unreadOnly := false
+	var folder sql.NullString
+	for i := 0; i < len(cmd.Args); i++ {
+		arg := cmd.Args[i]
+		if arg == "--unread" {
+			unreadOnly = true
+			continue
+		}
+		if arg == "--folder" {
+			if i+1 >= len(cmd.Args) {
+				return fmt.Errorf("--folder expects the tag of the feeds to browse")
+			}
+			i++
+			folder = sql.NullString{String: normalizeTag(cmd.Args[i]), Valid: true}
+			continue
+		}
+		num, err := strconv.Atoi(arg)
+		if err != nil || num < 1 {
+			s.LogError("Could not convert %v to an integer", arg)
+			return fmt.Errorf("browse expects the number of posts to show: %v", arg)
 		}
 		numPosts = int32(num)
 	}
 
-	ctx := context.Background()
+	ctx := s.Ctx
 	postParams := database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  numPosts,
+		UserID:      user.ID,
+		UnreadOnly:  unreadOnly,
+		Folder:      folder,
+		ResultLimit: numPosts,
 	}
 
 	s.LogInfo("Browsing %d number of posts:", numPosts)
 	posts, err := s.Db.GetPostsForUser(ctx, postParams)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			// No posts retrieved
+		s.LogError("Could not retrieve posts: %v", err)
+		return err
+	}
+
+	if len(posts) == 0 {
+		if folder.Valid {
+			s.LogInfo("No posts retrieved. Check if you tagged any feeds with %s.", folder.String)
+		} else if unreadOnly {
+			s.LogInfo("No unread posts, you are all caught up.")
+		} else {
 			s.LogInfo("No posts retrieved. Check if you are following any feeds.")
-			return nil
 		}
-		// Handle other errors
-		s.LogError(fmt.Sprintf("%v", err))
+		return nil
 	}
 
-	for i := int32(0); i < numPosts; i++ {
-		post := posts[i]
-		s.LogInfo(ColorGreen+"Title:"+ColorReset+" %v | "+ColorGreen+"Link:"+ColorReset+" %v ("+ColorGreen+"Published:"+ColorReset+" %v)", post.Title, post.Url, post.PublishedAt)
+	for _, post := range posts {
+		readMarker := ""
+		if post.Read {
+			readMarker = " [read]"
+		}
+		if post.Starred {
+			readMarker += " [starred]"
+		}
+		s.LogInfo("%s "+ColorGreen+"Title:"+ColorReset+" %v | "+ColorGreen+"Link:"+ColorReset+" %v ("+ColorGreen+"Published:"+ColorReset+" %v)%s", shortID(post.ID), post.Title, post.Url, post.PublishedAt, readMarker)
 	}
 
 	return nil
 }
+
+// middleware auth handles user
+func HandlerWatch(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s watching for new posts: args=%v", user.Name, cmd.Args)
+	pollInterval := 30 * time.Second
+	if len(cmd.Args) > 0 {
+		interval, err := time.ParseDuration(cmd.Args[0])
+		if err != nil {
+			s.LogError("Error parsing %s to time.Duration. Error was %v", cmd.Args[0], err)
+			return fmt.Errorf("error parsing %s\nerror was %v", cmd.Args[0], err)
+		}
+		// time.NewTicker panics on anything else
+		if interval <= 0 {
+			return fmt.Errorf("watch expects a positive poll interval: %v", cmd.Args[0])
+		}
+		pollInterval = interval
+	}
+
+	// Notifications from the aggregator wake us up straight away, polling covers missed ones
+	listener, postsAdded := listen(s, postsAddedChannel)
+	if listener != nil {
+		defer listener.Close()
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	ctx := s.Ctx
+	latest, err := s.Db.GetLatestPostSeq(ctx)
+	if err != nil {
+		s.LogError("Failed to find the latest post: %v", err)
+		return fmt.Errorf("failed to find the latest post: %w", err)
+	}
+	cursor := newPostCursor(latest)
+
+	s.LogInfo("Watching for new posts, press Ctrl-C to stop")
+	for {
+		posts, err := s.Db.GetPostsForUserSince(ctx, database.GetPostsForUserSinceParams{
+			UserID:   user.ID,
+			AfterSeq: cursor.floor,
+		})
+		if err != nil && ctx.Err() == nil {
+			s.LogError("Failed to query new posts: %v", err)
+		}
+
+		for _, post := range cursor.next(posts, time.Now()) {
+			s.LogInfo(ColorGreen+"[%s]"+ColorReset+" %v | "+ColorGreen+"Link:"+ColorReset+" %v ("+ColorGreen+"Published:"+ColorReset+" %v)", post.FeedName, post.Title, post.Url, post.PublishedAt)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-postsAdded:
+		case <-ctx.Done():
+			s.LogDebug("Stopped watching for new posts")
+			return nil
+		}
+	}
+}
+
+// watchLookback is how long watch asks again for posts numbered below the ones it has seen. Posts are
+// numbered when inserted but only show up once their transaction commits, so a batch can appear after
+// posts with higher numbers stored alongside it
+const watchLookback = 5 * time.Minute
+
+// postCursor keeps track of the posts watch has shown. It only compares post numbers and times taken
+// by watch itself, the clocks of the hosts running the aggregator don't matter
+type postCursor struct {
+	// floor is the highest seq seen at least watchLookback ago, no post at or below it is new
+	floor  int64
+	latest int64
+	seen   map[uuid.UUID]int64
+	polls  []cursorPoll
+}
+
+type cursorPoll struct {
+	at     time.Time
+	latest int64
+}
+
+func newPostCursor(latest int64) *postCursor {
+	return &postCursor{floor: latest, latest: latest, seen: map[uuid.UUID]int64{}}
+}
+
+// next returns the posts above the floor that weren't shown yet and moves the floor up once the posts
+// seen by an earlier poll are older than watchLookback
+func (c *postCursor) next(posts []database.GetPostsForUserSinceRow, now time.Time) []database.GetPostsForUserSinceRow {
+	var unseen []database.GetPostsForUserSinceRow
+	for _, post := range posts {
+		if _, ok := c.seen[post.ID]; ok || post.Seq <= c.floor {
+			continue
+		}
+		c.seen[post.ID] = post.Seq
+		c.latest = max(c.latest, post.Seq)
+		unseen = append(unseen, post)
+	}
+
+	c.polls = append(c.polls, cursorPoll{at: now, latest: c.latest})
+	for len(c.polls) > 0 && now.Sub(c.polls[0].at) >= watchLookback {
+		c.floor = c.polls[0].latest
+		c.polls = c.polls[1:]
+	}
+	for id, seq := range c.seen {
+		if seq <= c.floor {
+			delete(c.seen, id)
+		}
+	}
+	return unseen
+}
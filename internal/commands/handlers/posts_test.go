@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func watchedPost(title string, seq int64) database.GetPostsForUserSinceRow {
+	return database.GetPostsForUserSinceRow{ID: uuid.New(), Seq: seq, Title: title}
+}
+
+func watchedTitles(posts []database.GetPostsForUserSinceRow) []string {
+	var titles []string
+	for _, post := range posts {
+		titles = append(titles, post.Title)
+	}
+	return titles
+}
+
+func TestPostCursorShowsLateCommits(t *testing.T) {
+	start := time.Now()
+	cursor := newPostCursor(10)
+	early := watchedPost("Early", 11)
+	late := watchedPost("Late", 12)
+
+	// The batch holding 11 commits after the one holding 12
+	if got := watchedTitles(cursor.next([]database.GetPostsForUserSinceRow{late}, start)); !slices.Equal(got, []string{"Late"}) {
+		t.Fatalf("expected the committed post, got %v", got)
+	}
+	if cursor.floor != 10 {
+		t.Fatalf("the floor shouldn't move before the lookback is over, got %d", cursor.floor)
+	}
+
+	got := watchedTitles(cursor.next([]database.GetPostsForUserSinceRow{early, late}, start.Add(time.Minute)))
+	if !slices.Equal(got, []string{"Early"}) {
+		t.Errorf("expected only the post committed late, got %v", got)
+	}
+
+	// Once the lookback is over the floor catches up and only newer posts are asked for
+	cursor.next([]database.GetPostsForUserSinceRow{early, late}, start.Add(watchLookback))
+	if cursor.floor != 12 || len(cursor.seen) != 0 {
+		t.Errorf("expected the floor at 12 with nothing left to remember, got %d and %v", cursor.floor, cursor.seen)
+	}
+	if got := cursor.next([]database.GetPostsForUserSinceRow{watchedPost("Older", 9)}, start.Add(watchLookback)); len(got) != 0 {
+		t.Errorf("posts at or below the floor aren't new, got %v", watchedTitles(got))
+	}
+}
+
+func TestWatchedPostsIgnoreFetchTimes(t *testing.T) {
+	s, db := newTestState(t)
+	user := addUser(t, s, "bob")
+	server := serveFeed(t, testItem{Title: "First", PubDate: time.Now()})
+	feed := addTestFeed(t, s, user, server)
+
+	latest, err := s.Db.GetLatestPostSeq(s.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := newPostCursor(latest)
+
+	// An aggregator whose clock is an hour behind the watcher's
+	_, err = db.CreatePosts(s.Ctx, database.CreatePostsParams{
+		Now:          time.Now().Add(-time.Hour),
+		FeedID:       feed.ID,
+		Titles:       []string{"Behind"},
+		Urls:         []string{"https://example.com/behind"},
+		Descriptions: []string{""},
+		PublishedAts: []time.Time{time.Now()},
+		Contents:     []string{""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts, err := s.Db.GetPostsForUserSince(s.Ctx, database.GetPostsForUserSinceParams{UserID: user.ID, AfterSeq: cursor.floor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := watchedTitles(cursor.next(posts, time.Now())); !slices.Equal(got, []string{"Behind"}) {
+		t.Errorf("expected the new post whatever its creation time, got %v", got)
+	}
+}
+
+func TestWatchRejectsInvalidIntervals(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+
+	for _, interval := range []string{"0s", "-5s", "soon"} {
+		if err := HandlerWatch(s, commands.Command{Name: "watch", Args: []string{interval}}, user); err == nil {
+			t.Errorf("watch %s should have failed", interval)
+		}
+	}
+}
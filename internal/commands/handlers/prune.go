@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+func HandlerPrune(s *config.State, cmd commands.Command) error {
+	s.LogDebug("Pruning posts: args=%v", cmd.Args)
+
+	// --dry-run only counts what would be pruned, a feed url or name limits pruning to that feed
+	dryRun := false
+	feedRef := ""
+	for _, arg := range cmd.Args {
+		if arg == "--dry-run" {
+			dryRun = true
+			continue
+		}
+		if feedRef != "" {
+			return fmt.Errorf("prune expects at most one feed url or name: %v", cmd.Args)
+		}
+		feedRef = arg
+	}
+
+	ctx := s.Ctx
+	var feeds []database.Feed
+	var err error
+	if feedRef == "" {
+		feeds, err = s.Db.GetAllFeeds(ctx)
+	} else {
+		feeds, err = s.Db.GetFeedsByUrlOrName(ctx, feedRef)
+		if err == nil && len(feeds) == 0 {
+			s.LogError("No feed registered with url or name: %s", feedRef)
+			return fmt.Errorf("no feed has that url or name, %v", feedRef)
+		}
+	}
+	if err != nil {
+		s.LogError("Failed to query feeds to prune: %v", err)
+		return err
+	}
+
+	pruned, err := pruneFeeds(ctx, s, feeds, dryRun)
+	if dryRun {
+		s.LogInfo("Would prune %d posts", pruned)
+	} else {
+		s.LogInfo("Pruned %d posts", pruned)
+	}
+	return err
+}
+
+// middleware auth handles user
+func HandlerRetention(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s managing feed retention: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
+		return fmt.Errorf("retention expects the url or name of a feed and optionally forever, latest:<posts>, days:<days> or default: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	feeds, err := s.Db.GetFeedsByUrlOrName(ctx, cmd.Args[0])
+	if err != nil {
+		s.LogError("Failed to query feeds: %v", err)
+		return err
+	}
+	switch len(feeds) {
+	case 0:
+		s.LogError("No feed registered with url or name: %s", cmd.Args[0])
+		return fmt.Errorf("no feed has that url or name, %v", cmd.Args[0])
+	case 1:
+	default:
+		return fmt.Errorf("several feeds are named %q, pass the url instead", cmd.Args[0])
+	}
+	feed := feeds[0]
+
+	if len(cmd.Args) == 1 {
+		policy, err := retentionPolicy(s, feed)
+		if err != nil {
+			return err
+		}
+		if feed.Retention.Valid {
+			s.LogInfo("%s keeps posts: %s", feed.Name, policy)
+		} else {
+			s.LogInfo("%s keeps posts: %s (the default)", feed.Name, policy)
+		}
+		return nil
+	}
+
+	// Retention applies to every follower, so only the user who added the feed may change it
+	if feed.UserID != user.ID {
+		s.LogError("User %s tried to change the retention of feed %s added by someone else", user.Name, feed.Name)
+		return fmt.Errorf("only the user who added %s can change its retention", feed.Name)
+	}
+
+	var retention sql.NullString
+	if cmd.Args[1] != "default" {
+		policy, err := config.ParseRetentionPolicy(cmd.Args[1])
+		if err != nil {
+			return err
+		}
+		retention = sql.NullString{String: policy.String(), Valid: true}
+	}
+
+	err = s.Db.SetFeedRetention(ctx, database.SetFeedRetentionParams{
+		ID:        feed.ID,
+		Retention: retention,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		s.LogError("Could not set the retention of %s: %v", feed.Name, err)
+		return err
+	}
+
+	if retention.Valid {
+		s.LogInfo("%s now keeps posts: %s", feed.Name, retention.String)
+	} else {
+		s.LogInfo("%s now keeps posts per the default retention", feed.Name)
+	}
+	return nil
+}
+
+// prunePosts prunes every feed for the aggregator, which only logs failures
+func prunePosts(ctx context.Context, s *config.State) {
+	feeds, err := s.Db.GetAllFeeds(ctx)
+	if err != nil {
+		s.LogError("Failed to query feeds to prune: %v", err)
+		return
+	}
+
+	pruned, _ := pruneFeeds(ctx, s, feeds, false)
+	if pruned > 0 {
+		s.LogInfo("Pruned %d posts", pruned)
+	}
+}
+
+// retentionPolicy returns the feed's own retention policy, or the configured default when it has none
+func retentionPolicy(s *config.State, feed database.Feed) (config.RetentionPolicy, error) {
+	if feed.Retention.Valid {
+		return config.ParseRetentionPolicy(feed.Retention.String)
+	}
+	return s.Config.Agg.RetentionPolicy()
+}
+
+// retainedItems leaves out the items a feed's retention policy would prune, so pruned posts aren't
+// stored again while the feed still lists them
+func retainedItems(policy config.RetentionPolicy, items []RSSItem) []RSSItem {
+	switch policy.Mode {
+	case config.RetentionLatest:
+		return latestItems(items, policy.Limit)
+	case config.RetentionDays:
+		return itemsSince(items, time.Now().Add(-policy.MaxAge()))
+	default:
+		return items
+	}
+}
+
+// pruneFeeds deletes the posts of each feed its retention policy doesn't keep, or only counts them on a
+// dry run. A feed that fails is logged and the others are still pruned, the first error is returned
+func pruneFeeds(ctx context.Context, s *config.State, feeds []database.Feed, dryRun bool) (int64, error) {
+	var total int64
+	var firstErr error
+	for _, feed := range feeds {
+		policy, err := retentionPolicy(s, feed)
+		if err != nil {
+			s.LogError("Skipping feed %s: %v", feed.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		var publishedBefore sql.NullTime
+		var keepLatest sql.NullInt32
+		switch policy.Mode {
+		case config.RetentionLatest:
+			keepLatest = sql.NullInt32{Int32: int32(policy.Limit), Valid: true}
+		case config.RetentionDays:
+			publishedBefore = sql.NullTime{Time: time.Now().Add(-policy.MaxAge()), Valid: true}
+		default:
+			continue
+		}
+
+		var pruned int64
+		if dryRun {
+			pruned, err = s.Db.CountPrunablePosts(ctx, database.CountPrunablePostsParams{
+				FeedID:          feed.ID,
+				PublishedBefore: publishedBefore,
+				KeepLatest:      keepLatest,
+			})
+		} else {
+			pruned, err = s.Db.PrunePosts(ctx, database.PrunePostsParams{
+				FeedID:          feed.ID,
+				PublishedBefore: publishedBefore,
+				KeepLatest:      keepLatest,
+			})
+		}
+		if err != nil {
+			s.LogError("Could not prune feed %s: %v", feed.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if pruned > 0 {
+			s.LogInfo("%s: %d posts outside %s", feed.Name, pruned, policy)
+		}
+		total += pruned
+	}
+	return total, firstErr
+}
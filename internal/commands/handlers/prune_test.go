@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+func titles(items []RSSItem) []string {
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+func TestRetainedItems(t *testing.T) {
+	now := time.Now()
+	items := []RSSItem{
+		{Title: "Old", PubDate: now.Add(-30 * 24 * time.Hour).Format(time.RFC1123Z), FetchedAt: now},
+		{Title: "Undated", FetchedAt: now},
+		{Title: "New", PubDate: now.Add(-time.Hour).Format(time.RFC1123Z), FetchedAt: now},
+		{Title: "Unknown format", PubDate: "yesterday", FetchedAt: now.Add(-30 * 24 * time.Hour)},
+	}
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{policy: "forever", want: []string{"Old", "Undated", "New", "Unknown format"}},
+		// Items without a known date count as published when they were fetched
+		{policy: "days:7", want: []string{"Undated", "New"}},
+		{policy: "latest:2", want: []string{"Undated", "New"}},
+	}
+	for _, tt := range tests {
+		policy, err := config.ParseRetentionPolicy(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(retainedItems(policy, items)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.policy, tt.want, got)
+		}
+	}
+}
+
+func TestUndatedPostsArePublishedWhenFetched(t *testing.T) {
+	s, _ := newTestState(t)
+	s.Config.Agg.Retention = "days:7"
+	user := addUser(t, s, "bob")
+	server := serveFeed(t, testItem{Title: "Undated"})
+	addTestFeed(t, s, user, server)
+
+	before := time.Now()
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+
+	posts, _ := s.Db.GetPostsForUser(s.Ctx, database.GetPostsForUserParams{UserID: user.ID, ResultLimit: 10})
+	if len(posts) != 1 || posts[0].PublishedAt.Before(before) {
+		t.Fatalf("the undated post should be stored as published when it was fetched, got %+v", posts)
+	}
+
+	// Pruning agrees with what was stored
+	if err := HandlerPrune(s, commands.Command{Name: "prune"}); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if got := postsFor(t, s, user); len(got) != 1 {
+		t.Errorf("the undated post should survive pruning, got %v", got)
+	}
+}
+
+func TestPruneKeepsLatestAndStarredPosts(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	now := time.Now()
+	server := serveFeed(t,
+		testItem{Title: "Newest", PubDate: now.Add(-time.Hour)},
+		testItem{Title: "Middle", PubDate: now.Add(-2 * time.Hour)},
+		testItem{Title: "Oldest", PubDate: now.Add(-3 * time.Hour)},
+	)
+	feed := addTestFeed(t, s, user, server)
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+
+	if err := HandlerStar(s, commands.Command{Name: "star", Args: []string{"https://example.com/oldest"}}, user); err != nil {
+		t.Fatalf("star failed: %v", err)
+	}
+	if err := HandlerRetention(s, commands.Command{Name: "retention", Args: []string{feed.Name, "latest:1"}}, user); err != nil {
+		t.Fatalf("retention failed: %v", err)
+	}
+
+	if err := HandlerPrune(s, commands.Command{Name: "prune", Args: []string{"--dry-run"}}); err != nil {
+		t.Fatalf("prune --dry-run failed: %v", err)
+	}
+	if got := postsFor(t, s, user); len(got) != 3 {
+		t.Fatalf("a dry run shouldn't delete anything, got %v", got)
+	}
+
+	if err := HandlerPrune(s, commands.Command{Name: "prune"}); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if got := postsFor(t, s, user); !slices.Equal(got, []string{"Newest", "Oldest"}) {
+		t.Errorf("expected the latest and the starred post, got %v", got)
+	}
+}
+
+func TestRetentionOnlyByOwner(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	server := serveFeed(t)
+	feed := addTestFeed(t, s, bob, server)
+
+	err := HandlerRetention(s, commands.Command{Name: "retention", Args: []string{feed.Url, "days:7"}}, alice)
+	if err == nil {
+		t.Error("only the user who added a feed should change its retention")
+	}
+	if feed := getFeed(t, s, server.URL); feed.Retention.Valid {
+		t.Errorf("the retention shouldn't have changed, got %q", feed.Retention.String)
+	}
+
+	if err := HandlerRetention(s, commands.Command{Name: "retention", Args: []string{feed.Url, "days:7"}}, bob); err != nil {
+		t.Fatalf("retention failed: %v", err)
+	}
+	if feed := getFeed(t, s, server.URL); feed.Retention.String != "days:7" {
+		t.Errorf("expected days:7, got %q", feed.Retention.String)
+	}
+}
+
+func TestReparseAppliesRetention(t *testing.T) {
+	s, _ := newTestState(t)
+	s.Config.Archive.Enabled = true
+	user := addUser(t, s, "bob")
+	now := time.Now()
+	newest := testItem{Title: "Newest", PubDate: now.Add(-time.Hour)}
+	middle := testItem{Title: "Middle", PubDate: now.Add(-2 * time.Hour)}
+	oldest := testItem{Title: "Oldest", PubDate: now.Add(-3 * time.Hour)}
+	server := serveFeed(t, middle, oldest)
+	feed := addTestFeed(t, s, user, server)
+
+	// Two archived responses, the second with a new post
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("first scrape failed: %v", err)
+	}
+	server.Body = rssBody(newest, middle, oldest)
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("second scrape failed: %v", err)
+	}
+
+	if err := HandlerRetention(s, commands.Command{Name: "retention", Args: []string{feed.Url, "latest:2"}}, user); err != nil {
+		t.Fatalf("retention failed: %v", err)
+	}
+	if err := HandlerPrune(s, commands.Command{Name: "prune"}); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+
+	if err := HandlerReparse(s, commands.Command{Name: "reparse", Args: []string{feed.Url}}); err != nil {
+		t.Fatalf("reparse failed: %v", err)
+	}
+	if got := postsFor(t, s, user); !slices.Equal(got, []string{"Newest", "Middle"}) {
+		t.Errorf("reparse shouldn't bring back pruned posts, got %v", got)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// middleware auth handles user
+func HandlerRead(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s marking posts read: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("read expects the id (as shown by browse) or url of one or more posts: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	for _, ref := range cmd.Args {
+		post, err := findPost(ctx, s, user, ref)
+		if err != nil {
+			s.LogError("Could not mark %s read: %v", ref, err)
+			return err
+		}
+
+		err = s.Db.MarkPostRead(ctx, database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+			ReadAt: time.Now(),
+		})
+		if err != nil {
+			s.LogError("Could not mark %s read: %v", post.Title, err)
+			return err
+		}
+		s.LogInfo("Marked read: %s", post.Title)
+	}
+
+	return nil
+}
+
+// middleware auth handles user
+func HandlerUnread(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s marking posts unread: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("unread expects the id (as shown by browse) or url of one or more posts: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	for _, ref := range cmd.Args {
+		post, err := findPost(ctx, s, user, ref)
+		if err != nil {
+			s.LogError("Could not mark %s unread: %v", ref, err)
+			return err
+		}
+
+		err = s.Db.MarkPostUnread(ctx, database.MarkPostUnreadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		if err != nil {
+			s.LogError("Could not mark %s unread: %v", post.Title, err)
+			return err
+		}
+		s.LogInfo("Marked unread: %s", post.Title)
+	}
+
+	return nil
+}
+
+// middleware auth handles user
+func HandlerMarkRead(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s marking posts read in bulk: args=%v", user.Name, cmd.Args)
+
+	// --feed <url or name> and --before <date> narrow down what is marked read, --all marks everything.
+	// One of them is required so a stray markread doesn't empty your unread list
+	var feedRef string
+	var before sql.NullTime
+	all := false
+	for i := 0; i < len(cmd.Args); i++ {
+		switch cmd.Args[i] {
+		case "--all":
+			all = true
+		case "--feed", "--before":
+			if i+1 >= len(cmd.Args) {
+				return fmt.Errorf("%s expects a value", cmd.Args[i])
+			}
+			if cmd.Args[i] == "--feed" {
+				feedRef = cmd.Args[i+1]
+			} else {
+				parsed, err := parseDate(cmd.Args[i+1])
+				if err != nil {
+					return err
+				}
+				before = sql.NullTime{Time: parsed, Valid: true}
+			}
+			i++
+		default:
+			return fmt.Errorf("unknown markread argument %q, expected --feed <url or name>, --before <date> or --all", cmd.Args[i])
+		}
+	}
+	if !all && feedRef == "" && !before.Valid {
+		return fmt.Errorf("markread expects --feed <url or name>, --before <date> or --all")
+	}
+
+	ctx := s.Ctx
+	params := database.MarkPostsReadParams{
+		UserID:          user.ID,
+		ReadAt:          time.Now(),
+		PublishedBefore: before,
+	}
+
+	// Without --feed every feed you follow is covered in one go
+	feedIDs := []uuid.NullUUID{{}}
+	if feedRef != "" {
+		feeds, err := s.Db.GetFeedsByUrlOrName(ctx, feedRef)
+		if err != nil {
+			s.LogError("Failed to query feeds to mark read: %v", err)
+			return err
+		}
+		if len(feeds) == 0 {
+			s.LogError("No feed registered with url or name: %s", feedRef)
+			return fmt.Errorf("no feed has that url or name, %v", feedRef)
+		}
+
+		feedIDs = nil
+		for _, feed := range feeds {
+			feedIDs = append(feedIDs, uuid.NullUUID{UUID: feed.ID, Valid: true})
+		}
+	}
+
+	var marked int64
+	for _, feedID := range feedIDs {
+		params.FeedID = feedID
+		rows, err := s.Db.MarkPostsRead(ctx, params)
+		if err != nil {
+			s.LogError("Could not mark posts read: %v", err)
+			return err
+		}
+		marked += rows
+	}
+
+	s.LogInfo("Marked %d posts read", marked)
+	return nil
+}
+
+// parseDate reads a date like 2024-01-31, or a full RFC 3339 time
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected e.g. 2024-01-31", value)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// addFetchedFeed adds a feed named name serving items for user and fetches it
+func addFetchedFeed(t *testing.T, s *config.State, user database.User, name string, items ...testItem) database.Feed {
+	t.Helper()
+
+	server := serveFeed(t, items...)
+	if err := HandlerAddFeed(s, commands.Command{Name: "addfeed", Args: []string{name, server.URL}}, user); err != nil {
+		t.Fatalf("addfeed failed: %v", err)
+	}
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+	return getFeed(t, s, server.URL)
+}
+
+// postID returns the id browse shows for the post of user with title
+func postID(t *testing.T, s *config.State, user database.User, title string) string {
+	t.Helper()
+
+	posts, err := s.Db.GetPostsForUser(s.Ctx, database.GetPostsForUserParams{UserID: user.ID, ResultLimit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, post := range posts {
+		if post.Title == title {
+			return shortID(post.ID)
+		}
+	}
+	t.Fatalf("%s has no post %q", user.Name, title)
+	return ""
+}
+
+func TestReadAndUnread(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	now := time.Now()
+	feed := addFetchedFeed(t, s, bob, "Blog",
+		testItem{Title: "First", PubDate: now.Add(-time.Hour)},
+		testItem{Title: "Second", PubDate: now.Add(-2 * time.Hour)},
+		testItem{Title: "Third", PubDate: now.Add(-3 * time.Hour)},
+	)
+	if err := HandlerFollowFeed(s, commands.Command{Name: "follow", Args: []string{feed.Url}}, alice); err != nil {
+		t.Fatalf("follow failed: %v", err)
+	}
+
+	// Posts can be referred to by url or by the id browse shows
+	err := HandlerRead(s, commands.Command{Name: "read", Args: []string{"https://example.com/first", postID(t, s, bob, "Third")}}, bob)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if titles := browse(t, s, bob, "10", "--unread"); !slices.Equal(titles, []string{"Second"}) {
+		t.Errorf("expected only Second unread, got %v", titles)
+	}
+	if titles := browse(t, s, bob, "10"); !slices.Equal(titles, []string{"First", "Second", "Third"}) {
+		t.Errorf("browse without --unread should still list read posts, got %v", titles)
+	}
+	if titles := browse(t, s, alice, "10", "--unread"); len(titles) != 3 {
+		t.Errorf("posts bob read should be unread for alice, got %v", titles)
+	}
+
+	if err := HandlerUnread(s, commands.Command{Name: "unread", Args: []string{"https://example.com/first"}}, bob); err != nil {
+		t.Fatalf("unread failed: %v", err)
+	}
+	if titles := browse(t, s, bob, "10", "--unread"); !slices.Equal(titles, []string{"First", "Second"}) {
+		t.Errorf("expected First to be unread again, got %v", titles)
+	}
+
+	// The limit applies to the unread posts
+	if titles := browse(t, s, bob, "--unread", "1"); !slices.Equal(titles, []string{"First"}) {
+		t.Errorf("expected the newest unread post, got %v", titles)
+	}
+}
+
+func TestReadRejectsUnknownPosts(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	addFetchedFeed(t, s, bob, "Blog", testItem{Title: "First", PubDate: time.Now()})
+
+	for _, args := range [][]string{{}, {"https://example.com/missing"}} {
+		if err := HandlerRead(s, commands.Command{Name: "read", Args: args}, bob); err == nil {
+			t.Errorf("read %v should have failed", args)
+		}
+		if err := HandlerUnread(s, commands.Command{Name: "unread", Args: args}, bob); err == nil {
+			t.Errorf("unread %v should have failed", args)
+		}
+	}
+
+	// Only posts of feeds you follow can be marked
+	if err := HandlerRead(s, commands.Command{Name: "read", Args: []string{"https://example.com/first"}}, alice); err == nil {
+		t.Error("alice shouldn't be able to mark a post of a feed she doesn't follow")
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	day := 24 * time.Hour
+	now := time.Now()
+	addFetchedFeed(t, s, user, "Blog",
+		testItem{Title: "Blog new", PubDate: now.Add(-day)},
+		testItem{Title: "Blog old", PubDate: now.Add(-10 * day)},
+	)
+	addFetchedFeed(t, s, user, "News",
+		testItem{Title: "News new", PubDate: now.Add(-2 * day)},
+		testItem{Title: "News old", PubDate: now.Add(-20 * day)},
+	)
+
+	markRead := func(args ...string) error {
+		return HandlerMarkRead(s, commands.Command{Name: "markread", Args: args}, user)
+	}
+	for _, args := range [][]string{{}, {"--feed"}, {"--before", "yesterday"}, {"--everything"}, {"--feed", "Missing"}} {
+		if err := markRead(args...); err == nil {
+			t.Errorf("markread %v should have failed", args)
+		}
+	}
+	if titles := browse(t, s, user, "10", "--unread"); len(titles) != 4 {
+		t.Fatalf("failed markreads shouldn't mark anything, got %v unread", titles)
+	}
+
+	if err := markRead("--feed", "News"); err != nil {
+		t.Fatalf("markread --feed failed: %v", err)
+	}
+	if titles := browse(t, s, user, "10", "--unread"); !slices.Equal(titles, []string{"Blog new", "Blog old"}) {
+		t.Errorf("expected only the posts of Blog unread, got %v", titles)
+	}
+
+	if err := markRead("--before", now.Add(-5*day).Format(time.DateOnly)); err != nil {
+		t.Fatalf("markread --before failed: %v", err)
+	}
+	if titles := browse(t, s, user, "10", "--unread"); !slices.Equal(titles, []string{"Blog new"}) {
+		t.Errorf("expected the older post of Blog to be read, got %v", titles)
+	}
+
+	if err := markRead("--all"); err != nil {
+		t.Fatalf("markread --all failed: %v", err)
+	}
+	if titles := browse(t, s, user, "10", "--unread"); len(titles) != 0 {
+		t.Errorf("expected every post to be read, got %v", titles)
+	}
+}
+
+func TestReadTakesIDsLiterally(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	addFetchedFeed(t, s, user, "Blog", testItem{Title: "First", PubDate: time.Now()})
+
+	// LIKE wildcards would match the only post
+	for _, ref := range []string{"%", "_", "%-%"} {
+		if err := HandlerRead(s, commands.Command{Name: "read", Args: []string{ref}}, user); err == nil {
+			t.Errorf("read %q shouldn't find a post", ref)
+		}
+	}
+	if err := HandlerRead(s, commands.Command{Name: "read", Args: []string{postID(t, s, user, "First")}}, user); err != nil {
+		t.Errorf("read by id failed: %v", err)
+	}
+}
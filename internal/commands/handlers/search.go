@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+const defaultSearchLimit = 10
+
+// middleware auth handles user
+func HandlerSearch(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s searching posts: args=%v", user.Name, cmd.Args)
+
+	// --all searches every post instead of only the feeds you follow, --limit <n> caps the results
+	allFeeds := false
+	limit := defaultSearchLimit
+	var words []string
+	for i := 0; i < len(cmd.Args); i++ {
+		switch cmd.Args[i] {
+		case "--all":
+			allFeeds = true
+		case "--limit":
+			if i+1 >= len(cmd.Args) {
+				return fmt.Errorf("--limit expects the number of results to show")
+			}
+			i++
+			parsed, err := strconv.Atoi(cmd.Args[i])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid number of results %q", cmd.Args[i])
+			}
+			limit = parsed
+		default:
+			words = append(words, cmd.Args[i])
+		}
+	}
+
+	query := strings.Join(words, " ")
+	if !database.Searchable(database.ParseSearchQuery(query)) {
+		return fmt.Errorf("search expects words or \"quoted phrases\" to look for, -word excludes a word: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	results, err := s.Db.SearchPosts(ctx, database.SearchPostsParams{
+		Query:       query,
+		StartSel:    ColorYellow,
+		StopSel:     ColorReset,
+		AllFeeds:    allFeeds,
+		UserID:      user.ID,
+		ResultLimit: int32(limit),
+	})
+	if err != nil {
+		s.LogError("Could not search posts for %q: %v", query, err)
+		return err
+	}
+
+	if len(results) == 0 {
+		s.LogInfo("No posts found for %q", query)
+		return nil
+	}
+
+	s.LogInfo("Found %d posts for %q:", len(results), query)
+	for _, result := range results {
+		s.LogInfo(ColorGreen+"Title:"+ColorReset+" %v | "+ColorGreen+"Feed:"+ColorReset+" %v ("+ColorGreen+"Published:"+ColorReset+" %v, "+ColorGreen+"Score:"+ColorReset+" %.3g)",
+			result.Title, result.FeedName, result.PublishedAt.Format("Jan 02, 2006"), result.Score)
+		// Descriptions often span several lines, keep each snippet on one
+		if snippet := strings.Join(strings.Fields(result.Snippet), " "); snippet != "" {
+			s.LogInfo("    %v", snippet)
+		}
+		s.LogInfo("    %v", result.Url)
+	}
+
+	return nil
+}
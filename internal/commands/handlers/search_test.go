@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// search runs the search command with args and returns what it logged
+func search(t *testing.T, s *config.State, user database.User, args ...string) string {
+	t.Helper()
+
+	out, err := logged(t, s, func() error {
+		return HandlerSearch(s, commands.Command{Name: "search", Args: args}, user)
+	})
+	if err != nil {
+		t.Fatalf("search %v failed: %v", args, err)
+	}
+	return out
+}
+
+// foundTitles returns the titles of the results search logged, best match first, without highlighting
+func foundTitles(out string) []string {
+	var titles []string
+	for _, match := range browsedTitle.FindAllStringSubmatch(out, -1) {
+		title := strings.ReplaceAll(match[1], ColorYellow, "")
+		titles = append(titles, strings.ReplaceAll(title, ColorReset, ""))
+	}
+	return titles
+}
+
+func TestSearchMatches(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	now := time.Now()
+	addFetchedFeed(t, s, bob, "Blog",
+		testItem{Title: "Postgres vacuum explained", PubDate: now.Add(-time.Hour)},
+		testItem{Title: "Tuning autovacuum", Description: "How Postgres cleans up dead rows", PubDate: now.Add(-2 * time.Hour)},
+		testItem{Title: "MySQL purge", Description: "The MySQL take on vacuum", PubDate: now.Add(-3 * time.Hour)},
+	)
+	addFetchedFeed(t, s, alice, "News", testItem{Title: "Postgres 18 released", PubDate: now.Add(-4 * time.Hour)})
+
+	tests := []struct {
+		args   []string
+		titles []string
+	}{
+		// Titles weigh more than descriptions
+		{args: []string{"postgres"}, titles: []string{"Postgres vacuum explained", "Tuning autovacuum"}},
+		{args: []string{"vacuum", "-mysql"}, titles: []string{"Postgres vacuum explained"}},
+		{args: []string{`"dead rows"`}, titles: []string{"Tuning autovacuum"}},
+		{args: []string{`"rows dead"`}, titles: nil},
+		{args: []string{"cleans", "or", "explained"}, titles: []string{"Postgres vacuum explained", "Tuning autovacuum"}},
+		// Words are stemmed
+		{args: []string{"cleaning"}, titles: []string{"Tuning autovacuum"}},
+		{args: []string{"postgres", "--limit", "1"}, titles: []string{"Postgres vacuum explained"}},
+		// Only the feeds you follow are searched unless --all is passed
+		{args: []string{"released"}, titles: nil},
+		{args: []string{"released", "--all"}, titles: []string{"Postgres 18 released"}},
+	}
+	for _, tt := range tests {
+		if titles := foundTitles(search(t, s, bob, tt.args...)); !slices.Equal(titles, tt.titles) {
+			t.Errorf("search %v: expected %q, got %q", tt.args, tt.titles, titles)
+		}
+	}
+}
+
+func TestSearchHighlightsMatches(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	addFetchedFeed(t, s, user, "Blog", testItem{
+		Title:       "Postgres vacuum explained",
+		Description: "Vacuum reclaims the space of dead rows",
+		PubDate:     time.Now(),
+	})
+
+	out := search(t, s, user, "vacuum")
+	if !strings.Contains(out, "Postgres "+ColorYellow+"vacuum"+ColorReset+" explained") {
+		t.Errorf("the match in the title should be highlighted, got %q", out)
+	}
+	if !strings.Contains(out, ColorYellow+"Vacuum"+ColorReset+" reclaims") {
+		t.Errorf("the snippet of the description should be highlighted, got %q", out)
+	}
+}
+
+func TestSearchRejectsInvalidArguments(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+
+	for _, args := range [][]string{{}, {"-vacuum"}, {"vacuum", "--limit"}, {"vacuum", "--limit", "0"}} {
+		if err := HandlerSearch(s, commands.Command{Name: "search", Args: args}, user); err == nil {
+			t.Errorf("search %v should have failed", args)
+		}
+	}
+}
@@ -2,80 +2,403 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/git-cst/bootdev_gator/internal/commands"
 	"github.com/git-cst/bootdev_gator/internal/config"
 	"github.com/git-cst/bootdev_gator/internal/database"
-	"github.com/lib/pq"
 )
 
 func HandlerAgg(s *config.State, cmd commands.Command) error {
 	s.LogInfo("Start aggregator service")
-	if len(cmd.Args) < 1 {
+	once := false
+	durationString := ""
+	for _, arg := range cmd.Args {
+		if arg == "--once" {
+			once = true
+			continue
+		}
+		durationString = arg
+	}
+
+	if durationString == "" && !once {
 		return fmt.Errorf("aggregator command expected to receive time duration string as command: %s", cmd.Args)
 	}
-	durationString := cmd.Args[0]
 
-	timeBetweenReqs, err := time.ParseDuration(durationString)
+	var timeBetweenReqs time.Duration
+	if durationString != "" {
+		var err error
+		timeBetweenReqs, err = time.ParseDuration(durationString)
+		if err != nil {
+			s.LogError("Error parsing %s to time.Duration. Error was %v", durationString, err)
+			return fmt.Errorf("error parsing %s\nerror was %v", durationString, err)
+		}
+	}
+
+	grace, err := s.Config.Agg.Grace()
+	if err != nil {
+		s.LogError("Error reading aggregator config: %v", err)
+		return err
+	}
+	pruneEvery, err := s.Config.Agg.PruneEvery()
 	if err != nil {
-		s.LogError("Error parsing %s to time.Duration. Error was %v", timeBetweenReqs, err)
-		return fmt.Errorf("error parsing %s\nerror was %v", timeBetweenReqs, err)
+		s.LogError("Error reading aggregator config: %v", err)
+		return err
+	}
+
+	// Fetches run on a context that outlives the shutdown signal so they can finish within the grace period
+	fetchCtx, cancelFetches := context.WithCancel(context.WithoutCancel(s.Ctx))
+	defer cancelFetches()
+
+	if once {
+		return aggOnce(fetchCtx, cancelFetches, s, timeBetweenReqs, grace)
+	}
+
+	// Hubs verify subscriptions and push content to this endpoint
+	if s.Config.WebSub.Enabled() {
+		server := newWebSubServer(s)
+		go func() {
+			s.LogInfo("WebSub callback endpoint listening on %s", server.Addr)
+			err := server.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.LogError("WebSub callback endpoint stopped: %v", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(s.Ctx), grace)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+	}
+
+	// Renew WebSub leases well before they run out, at least two ticks ahead
+	renewMargin := max(2*timeBetweenReqs, time.Hour)
+
+	// Newly added feeds are announced over Postgres NOTIFY so they can be fetched right away
+	listener, feedsAdded := listen(s, feedAddedChannel)
+	if listener != nil {
+		defer listener.Close()
+	}
+
+	// runFetch runs fetch in the background and waits for it, false means shutdown was requested meanwhile
+	runFetch := func(fetch func()) bool {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			fetch()
+		}()
+
+		select {
+		case <-done:
+			return true
+		case <-s.Ctx.Done():
+			waitForInFlight(s, done, grace, cancelFetches)
+			return false
+		}
 	}
 
 	ticker := time.NewTicker(timeBetweenReqs)
+	defer ticker.Stop()
 	s.LogInfo("Collecting feeds every %v", timeBetweenReqs)
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+	var lastPrune time.Time
+	for {
+		fetch := func() {
+			scrapeFeeds(fetchCtx, s)
+			if s.Config.WebSub.Enabled() {
+				renewWebSubSubscriptions(fetchCtx, s, renewMargin)
+			}
+			// Pruning happens between fetches, at most once per prune interval
+			if pruneEvery > 0 && time.Since(lastPrune) >= pruneEvery {
+				prunePosts(fetchCtx, s)
+				lastPrune = time.Now()
+			}
+		}
+		if !runFetch(fetch) {
+			s.LogInfo("Aggregator service stopped")
+			return nil
+		}
+
+	wait:
+		for {
+			select {
+			case <-ticker.C:
+				break wait
+			case notification := <-feedsAdded:
+				if !runFetch(func() { fetchAddedFeed(fetchCtx, s, notification) }) {
+					s.LogInfo("Aggregator service stopped")
+					return nil
+				}
+			case <-s.Ctx.Done():
+				s.LogInfo("Aggregator service stopped")
+				return nil
+			}
+		}
+	}
+}
+
+// waitForInFlight gives running fetches the grace period to finish after shutdown was requested
+func waitForInFlight(s *config.State, done <-chan struct{}, grace time.Duration, cancelFetches context.CancelFunc) {
+	s.LogInfo("Shutdown requested, waiting up to %v for in-flight fetches to finish", grace)
+	select {
+	case <-done:
+	case <-time.After(grace):
+		s.LogError("In-flight fetches did not finish within %v, cancelling them", grace)
+		cancelFetches()
+		<-done
+	}
+}
+
+// aggOnce fetches every feed not fetched within interval, then prints a summary and returns
+func aggOnce(ctx context.Context, cancelFetches context.CancelFunc, s *config.State, interval time.Duration, grace time.Duration) error {
+	// Fixed up front so feeds fetched during this run don't become due again
+	dueBefore := sql.NullTime{
+		Time:  time.Now().Add(-interval),
+		Valid: true,
+	}
+
+	workers := s.Config.Agg.Workers()
+	s.LogInfo("Fetching due feeds with %d workers", workers)
+
+	type outcome struct {
+		feed   database.Feed
+		result fetchResult
+		err    error
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var outcomes []outcome
+	var claimErr error
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Workers keep claiming due feeds until none are left or shutdown is requested
+			for s.Ctx.Err() == nil {
+				lease, err := feedLease(s)
+				if err != nil {
+					mu.Lock()
+					claimErr = err
+					mu.Unlock()
+					return
+				}
+
+				feed, err := s.Db.ClaimDueFeed(ctx, database.ClaimDueFeedParams{
+					Now:          time.Now(),
+					LeaseSeconds: lease.Seconds(),
+					DueBefore:    dueBefore,
+				})
+				if err != nil {
+					if !errors.Is(err, sql.ErrNoRows) {
+						s.LogError("Failed to claim next due feed: %v", err)
+						mu.Lock()
+						claimErr = err
+						mu.Unlock()
+					}
+					return
+				}
+
+				result, err := fetchClaimedFeed(ctx, s, feed)
+				mu.Lock()
+				outcomes = append(outcomes, outcome{feed: feed, result: result, err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-s.Ctx.Done():
+		waitForInFlight(s, done, grace, cancelFetches)
+	}
+
+	if claimErr != nil {
+		return claimErr
+	}
+
+	if len(outcomes) == 0 {
+		s.LogInfo("No feeds are due for fetching")
+		return nil
+	}
+
+	failed := 0
+	newPosts := 0
+	for _, o := range outcomes {
+		if o.err != nil {
+			failed++
+			s.LogInfo(ColorRed+"%s: failed: %v"+ColorReset, o.feed.Name, o.err)
+			continue
+		}
+		newPosts += o.result.ItemsInserted
+		s.LogInfo(ColorGreen+"%s:"+ColorReset+" %d new posts (%d items in feed, %v)", o.feed.Name, o.result.ItemsInserted, o.result.ItemsSeen, o.result.Duration.Round(time.Millisecond))
+	}
+
+	s.LogInfo("Fetched %d feeds: %d succeeded, %d failed, %d new posts", len(outcomes), len(outcomes)-failed, failed, newPosts)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d feeds failed to fetch", failed, len(outcomes))
+	}
+
+	return nil
+}
+
+func HandlerRefresh(s *config.State, cmd commands.Command) error {
+	s.LogDebug("Refreshing feeds: args=%v", cmd.Args)
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("refresh expects a feed url, a feed name or --all: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	var feeds []database.Feed
+	var err error
+	if cmd.Args[0] == "--all" {
+		feeds, err = s.Db.GetAllFeeds(ctx)
+	} else {
+		feeds, err = s.Db.GetFeedsByUrlOrName(ctx, cmd.Args[0])
+	}
+	if err != nil {
+		s.LogError("Failed to query feeds to refresh: %v", err)
+		return err
+	}
+
+	if len(feeds) == 0 {
+		s.LogError("No feed registered with url or name: %s", cmd.Args[0])
+		return fmt.Errorf("no feed has that url or name, %v", cmd.Args[0])
+	}
+
+	failed := 0
+	for _, feed := range feeds {
+		result, err := refreshFeed(ctx, s, feed)
+		if err != nil {
+			failed++
+			s.LogInfo(ColorRed+"%s: failed: %v"+ColorReset, feed.Name, err)
+			continue
+		}
+		s.LogInfo(ColorGreen+"%s:"+ColorReset+" %d new posts stored (%d items in feed)", feed.Name, result.ItemsInserted, result.ItemsSeen)
 	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d feeds failed to refresh", failed, len(feeds))
+	}
+
+	s.LogDebug("Successfully refreshed %d feeds", len(feeds))
+	return nil
 }
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Items       []RSSItem `xml:"item"`
+		Title string `xml:"title"`
+		// Must come before Link, a field without namespace would also match atom:link elements
+		Links       []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Items       []RSSItem  `xml:"item"`
 	} `xml:"channel"`
 }
 
+// AtomLink is an <atom:link> element, feeds use these to advertise their WebSub hub
+type AtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
 type RSSItem struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	// The full text many feeds add in <content:encoded>
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	// FetchedAt is when the item was fetched, it stands in for a missing or unknown publication date
+	FetchedAt time.Time `xml:"-"`
+}
+
+// publishedAt returns when the item was published. Items without a (known) date count as published when
+// they were fetched, so they are imported, stored and pruned like an item published then
+func (item RSSItem) publishedAt() time.Time {
+	publishedAt, err := parseTimeString(item.PubDate)
+	if err != nil {
+		return item.FetchedAt
+	}
+	return publishedAt
 }
 
-func fetchFeed(c *config.Config, ctx context.Context, feedUrl string) (*RSSFeed, error) {
+// feedResponse describes the HTTP side of fetching a feed
+type feedResponse struct {
+	Status    int
+	Bytes     int64
+	Header    http.Header
+	Body      []byte
+	FetchedAt time.Time
+}
+
+// fetchFeed downloads a feed, parsing is left to the caller so unchanged bodies can be skipped
+func fetchFeed(c *config.Config, ctx context.Context, feedUrl string, headers map[string]string) (feedResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedUrl, nil)
 	if err != nil {
-		return &RSSFeed{}, err
+		return feedResponse{}, err
+	}
+
+	// Per feed headers (credentials, cookies, etc.), which aren't passed on to other hosts on a redirect
+	names := make([]string, 0, len(headers))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+		names = append(names, key)
 	}
+	req = req.WithContext(config.WithCredentialHeaders(ctx, names...))
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return &RSSFeed{}, err
+		return feedResponse{}, err
 	}
+	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
+	response := feedResponse{
+		Status:    resp.StatusCode,
+		Bytes:     int64(len(data)),
+		Header:    resp.Header,
+		Body:      data,
+		FetchedAt: time.Now(),
+	}
 	if err != nil {
-		return &RSSFeed{}, err
+		return response, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return response, fmt.Errorf("unexpected response status: %s", resp.Status)
 	}
 
+	return response, nil
+}
+
+func parseFeed(data []byte, fetchedAt time.Time) (*RSSFeed, error) {
 	var feed RSSFeed
-	xml.Unmarshal(data, &feed)
+	err := xml.Unmarshal(data, &feed)
+	if err != nil {
+		return &RSSFeed{}, fmt.Errorf("error parsing feed: %v", err)
+	}
 
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
 
 	for i := range feed.Channel.Items {
+		feed.Channel.Items[i].FetchedAt = fetchedAt
 		feed.Channel.Items[i].Title = html.UnescapeString(feed.Channel.Items[i].Title)
 		feed.Channel.Items[i].Description = html.UnescapeString(feed.Channel.Items[i].Description)
 	}
@@ -83,88 +406,332 @@ func fetchFeed(c *config.Config, ctx context.Context, feedUrl string) (*RSSFeed,
 	return &feed, nil
 }
 
-func scrapeFeeds(s *config.State) error {
+func scrapeFeeds(ctx context.Context, s *config.State) error {
 	s.LogDebug("Start scraping process")
-	ctx := context.Background()
-	feed, err := s.Db.GetNextFeedToFetch(ctx)
+	lease, err := feedLease(s)
+	if err != nil {
+		return err
+	}
+
+	// Feeds with an active WebSub subscription are only polled once they haven't been fetched since pushedBefore
+	pushedBefore := time.Now()
+	if s.Config.WebSub.Enabled() {
+		pollInterval, err := s.Config.WebSub.FallbackPollInterval()
+		if err != nil {
+			s.LogError("Error reading websub config: %v", err)
+			return err
+		}
+		pushedBefore = pushedBefore.Add(-pollInterval)
+	}
+
+	// Claiming is atomic so several aggregator instances never fetch the same feed
+	feed, err := s.Db.ClaimNextFeedToFetch(ctx, database.ClaimNextFeedToFetchParams{
+		Now:          time.Now(),
+		LeaseSeconds: lease.Seconds(),
+		PushedBefore: sql.NullTime{
+			Time:  pushedBefore,
+			Valid: true,
+		},
+	})
 	if err != nil {
 		// Handle no rows error
 		if errors.Is(err, sql.ErrNoRows) {
-			s.LogInfo("No feeds registered in database")
-			return fmt.Errorf("no feeds registered in database")
+			s.LogInfo("No feeds available to fetch (none registered or all claimed by other instances)")
+			return fmt.Errorf("no feeds available to fetch")
 		}
 		// Handle other errors
-		s.LogError("Failed to get next feed: %v", err)
+		s.LogError("Failed to claim next feed: %v", err)
 		return err
 	}
 
-	markFetchedParams := database.MarkFeedFetchedParams{
-		UpdatedAt: time.Now(),
-		LastFetchedAt: sql.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		ID: feed.ID,
+	_, err = fetchClaimedFeed(ctx, s, feed)
+	if err != nil {
+		return err
 	}
-	fetchedFeed, err := s.Db.MarkFeedFetched(ctx, markFetchedParams)
+
+	s.LogDebug("Feed scraping completed successfully")
+	return nil
+}
+
+// refreshFeed claims a specific feed and runs it through the fetch pipeline
+func refreshFeed(ctx context.Context, s *config.State, feed database.Feed) (fetchResult, error) {
+	lease, err := feedLease(s)
+	if err != nil {
+		return fetchResult{}, err
+	}
+
+	claimedFeed, err := s.Db.ClaimFeed(ctx, database.ClaimFeedParams{
+		Now:          time.Now(),
+		LeaseSeconds: lease.Seconds(),
+		ID:           feed.ID,
+	})
 	if err != nil {
 		// Handle no rows error
 		if errors.Is(err, sql.ErrNoRows) {
-			s.LogError("No feed returned by mark feed fetched.")
-			return fmt.Errorf("no feed returned by mark feed fetched")
+			s.LogInfo("Feed %s is being fetched by another instance", feed.Name)
+			return fetchResult{}, fmt.Errorf("feed is being fetched by another instance")
 		}
 		// Handle other errors
+		return fetchResult{}, err
+	}
+
+	return fetchClaimedFeed(ctx, s, claimedFeed)
+}
+
+// feedLease returns how long a claimed feed stays reserved for this instance. The database adds it
+// to its own clock, so instances with skewed clocks still agree on when a lease runs out
+func feedLease(s *config.State) (time.Duration, error) {
+	lease, err := s.Config.Agg.Lease()
+	if err != nil {
+		s.LogError("Error reading aggregator config: %v", err)
+		return 0, err
+	}
+	return lease, nil
+}
+
+// fetchClaimedFeed processes a claimed feed and gives up the claim afterwards
+func fetchClaimedFeed(ctx context.Context, s *config.State, feed database.Feed) (fetchResult, error) {
+	result, err := processFeed(ctx, s, feed)
+
+	// Release even when the fetch was cancelled, otherwise the feed stays claimed until the lease expires
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	releaseErr := s.Db.ReleaseFeedLease(releaseCtx, database.ReleaseFeedLeaseParams{
+		ID:            feed.ID,
+		LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if releaseErr != nil {
+		s.LogError("Could not release claim on feed %s (%v): %v", feed.Name, feed.ID, releaseErr)
+	}
+
+	return result, err
+}
+
+// fetchResult is what a single fetch of a feed did, it is recorded in the feed_fetches table
+type fetchResult struct {
+	StartedAt     time.Time
+	Duration      time.Duration
+	HTTPStatus    int
+	Bytes         int64
+	ItemsSeen     int
+	ItemsInserted int
+	// Unchanged is set when the body was identical to the previous fetch and wasn't parsed
+	Unchanged bool
+}
+
+// processFeed fetches a feed and stores its new posts, every attempt is recorded in feed_fetches
+func processFeed(ctx context.Context, s *config.State, feed database.Feed) (fetchResult, error) {
+	result := fetchResult{StartedAt: time.Now()}
+	err := fetchAndStoreFeed(ctx, s, feed, &result)
+	result.Duration = time.Since(result.StartedAt)
+	if err != nil {
+		s.LogError("Failed to fetch feed %s (%v): %v", feed.Name, feed.ID, err)
+	}
+
+	recordFetch(ctx, s, feed, result, err)
+	return result, err
+}
+
+func fetchAndStoreFeed(ctx context.Context, s *config.State, feed database.Feed, result *fetchResult) error {
+	headers, err := feedAuthHeaders(ctx, s, feed.ID)
+	if err != nil {
+		return fmt.Errorf("could not load credentials: %v", err)
+	}
+
+	// The label ties retries in the log to the feed
+	response, err := fetchFeed(s.Config, config.WithRequestLabel(ctx, feed.Name), feed.Url, headers)
+	result.HTTPStatus = response.Status
+	result.Bytes = response.Bytes
+	if err != nil {
 		return err
 	}
 
-	rssFeed, err := fetchFeed(s.Config, ctx, fetchedFeed.Url)
+	// A byte-identical body can't contain anything new, so don't parse or store it again
+	sum := sha256.Sum256(response.Body)
+	bodyHash := hex.EncodeToString(sum[:])
+	if feed.LastBodyHash.Valid && feed.LastBodyHash.String == bodyHash {
+		s.LogDebug("Feed %v is unchanged since the last fetch", feed.Name)
+		result.Unchanged = true
+		return nil
+	}
+
+	// Archive before looking at parse errors, those bodies are the ones worth reparsing later
+	if s.Config.Archive.Enabled {
+		archiveResponse(ctx, s, feed, response)
+	}
+
+	rssFeed, err := parseFeed(response.Body, response.FetchedAt)
 	if err != nil {
 		return err
 	}
 
-	s.LogInfo("Fetching feeds from %v", fetchedFeed.Name)
-	for _, item := range rssFeed.Channel.Items {
-		var descriptionNull sql.NullString
-		if item.Description != "" {
-			descriptionNull = sql.NullString{
-				String: item.Description,
-				Valid:  true,
-			}
-		} else {
-			descriptionNull = sql.NullString{
-				Valid: false,
-			}
+	// first_fetched_at is only set once posts were stored, failed fetches before that don't count
+	items := rssFeed.Channel.Items
+	policy := config.FirstFetchPolicy{Mode: config.FirstFetchAll}
+	firstFetch := !feed.FirstFetchedAt.Valid
+	if firstFetch {
+		policy, err = firstFetchPolicy(s, feed)
+		if err != nil {
+			return err
 		}
+		items = firstFetchItems(policy, items)
+		s.LogInfo("First fetch of %v, importing %d of %d items (%v)", feed.Name, len(items), len(rssFeed.Channel.Items), policy)
+	}
+
+	s.LogInfo("Fetching feeds from %v", feed.Name)
+	result.ItemsSeen = len(rssFeed.Channel.Items)
 
-		publishedAt, err := parseTimeString(item.PubDate)
+	// The posts, their read state and the body hash are stored together, so a failure part way
+	// can't leave the body marked as seen with its posts missing
+	err = s.Db.InTx(ctx, func(q database.Querier) error {
+		inserted, err := storePosts(ctx, s, q, feed, items)
 		if err != nil {
-			s.LogError("Unknown time format %v, err: %v", item.PubDate, err)
+			return err
 		}
+		result.ItemsInserted = inserted
+
+		if firstFetch {
+			if policy.Mode == config.FirstFetchMarkRead {
+				err := markFirstFetchRead(ctx, q, feed)
+				if err != nil {
+					return err
+				}
+			}
 
-		createPostParams := database.CreatePostParams{
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       item.Title,
-			Url:         item.Link,
-			Description: descriptionNull,
-			PublishedAt: publishedAt,
-			FeedID:      fetchedFeed.ID,
+			err := q.SetFeedFirstFetchedAt(ctx, database.SetFeedFirstFetchedAtParams{
+				ID:             feed.ID,
+				FirstFetchedAt: sql.NullTime{Time: response.FetchedAt, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("could not store first fetch time: %v", err)
+			}
 		}
 
-		_, err = s.Db.CreatePost(ctx, createPostParams)
+		// Only remember bodies that were parsed, a failed parse is retried on the next fetch
+		err = q.SetFeedBodyHash(ctx, database.SetFeedBodyHashParams{
+			ID:           feed.ID,
+			LastBodyHash: sql.NullString{String: bodyHash, Valid: true},
+		})
 		if err != nil {
-			if pqErr, ok := err.(*pq.Error); ok {
-				if pqErr.Code == "23505" { // PostgreSQL error code for unique violation
-					// OK expected behaviour
-					continue
-				}
-				// Log the error
-				s.LogError("Could not create the post for fetched feed %s (%v). Item failed was %s", fetchedFeed.Name, fetchedFeed.ID, item.Title)
-			}
+			return fmt.Errorf("could not store body hash: %v", err)
 		}
-		s.LogInfo(" - Post title: %s (Published: %s)", item.Title, item.PubDate)
+		return nil
+	})
+	if err != nil {
+		result.ItemsInserted = 0
+		return err
 	}
 
-	s.LogDebug("Feed scraping completed successfully")
+	if policy.Mode != config.FirstFetchMarkRead && result.ItemsInserted > 0 {
+		// Wake up anyone watching for new posts
+		notify(ctx, s, postsAddedChannel, feed.ID.String())
+	}
+
+	// Feeds advertising a hub can push updates to us instead of being polled
+	if s.Config.WebSub.Enabled() {
+		hub, topic := discoverHub(response.Header, rssFeed)
+		if hub != "" {
+			if topic == "" {
+				topic = feed.Url
+			}
+			err := ensureWebSubSubscription(ctx, s, feed, hub, topic)
+			if err != nil {
+				s.LogError("Could not subscribe to WebSub hub %s for feed %s: %v", hub, feed.Name, err)
+			}
+		}
+	}
 	return nil
 }
+
+// storePosts inserts the items of a feed as posts in a single statement and returns how many were new.
+// Items already stored, and items the feed's retention policy would prune, are skipped
+func storePosts(ctx context.Context, s *config.State, q database.Querier, feed database.Feed, items []RSSItem) (int, error) {
+	retention, err := retentionPolicy(s, feed)
+	if err != nil {
+		return 0, err
+	}
+	items = retainedItems(retention, items)
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	params := database.CreatePostsParams{
+		Now:    time.Now(),
+		FeedID: feed.ID,
+	}
+	for _, item := range items {
+		post := newPostParams(s, feed, item)
+		params.Titles = append(params.Titles, post.Title)
+		params.Urls = append(params.Urls, post.Url)
+		// Empty descriptions and contents are stored as NULL
+		params.Descriptions = append(params.Descriptions, post.Description.String)
+		params.PublishedAts = append(params.PublishedAts, post.PublishedAt)
+		params.Contents = append(params.Contents, post.Content.String)
+	}
+
+	posts, err := q.CreatePosts(ctx, params)
+	if err != nil {
+		return 0, fmt.Errorf("could not store the posts of feed %s (%v): %v", feed.Name, feed.ID, err)
+	}
+
+	for _, post := range posts {
+		s.LogInfo(" - Post title: %s (Published: %s)", post.Title, post.PublishedAt.Format(time.RFC1123Z))
+	}
+	return len(posts), nil
+}
+
+// newPostParams turns a feed item into the values stored for a post
+func newPostParams(s *config.State, feed database.Feed, item RSSItem) database.UpsertPostParams {
+	var descriptionNull sql.NullString
+	if item.Description != "" {
+		descriptionNull = sql.NullString{
+			String: item.Description,
+			Valid:  true,
+		}
+	} else {
+		descriptionNull = sql.NullString{
+			Valid: false,
+		}
+	}
+
+	_, err := parseTimeString(item.PubDate)
+	if err != nil {
+		s.LogError("Unknown time format %v, using the time it was fetched instead, err: %v", item.PubDate, err)
+	}
+
+	return database.UpsertPostParams{
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Title:       item.Title,
+		Url:         item.Link,
+		Description: descriptionNull,
+		PublishedAt: item.publishedAt(),
+		FeedID:      feed.ID,
+		Content:     sql.NullString{String: item.Content, Valid: item.Content != ""},
+	}
+}
+
+func recordFetch(ctx context.Context, s *config.State, feed database.Feed, result fetchResult, fetchErr error) {
+	// Record the attempt even if the fetch itself was cancelled during shutdown
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	params := database.CreateFeedFetchParams{
+		FeedID:        feed.ID,
+		StartedAt:     result.StartedAt,
+		DurationMs:    int32(result.Duration.Milliseconds()),
+		HttpStatus:    sql.NullInt32{Int32: int32(result.HTTPStatus), Valid: result.HTTPStatus != 0},
+		Bytes:         result.Bytes,
+		ItemsSeen:     int32(result.ItemsSeen),
+		ItemsInserted: int32(result.ItemsInserted),
+		Unchanged:     result.Unchanged,
+	}
+	if fetchErr != nil {
+		params.Error = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	_, err := s.Db.CreateFeedFetch(ctx, params)
+	if err != nil {
+		s.LogError("Could not record fetch of feed %s (%v): %v", feed.Name, feed.ID, err)
+	}
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// lastFetch returns the most recent fetch recorded for the feed
+func lastFetch(t *testing.T, s *config.State, feed database.Feed) database.FeedFetch {
+	t.Helper()
+
+	fetches, err := s.Db.GetRecentFeedFetches(s.Ctx, database.GetRecentFeedFetchesParams{FeedID: feed.ID, Limit: 1})
+	if err != nil || len(fetches) != 1 {
+		t.Fatalf("expected a recorded fetch of %s, got %d (%v)", feed.Name, len(fetches), err)
+	}
+	return fetches[0]
+}
+
+// addTestFeed adds a feed served by server for user, the server must be running already
+func addTestFeed(t *testing.T, s *config.State, user database.User, server *feedServer, args ...string) database.Feed {
+	t.Helper()
+
+	cmd := commands.Command{Name: "addfeed", Args: append([]string{"Blog", server.URL}, args...)}
+	if err := HandlerAddFeed(s, cmd, user); err != nil {
+		t.Fatalf("addfeed failed: %v", err)
+	}
+	return getFeed(t, s, server.URL)
+}
+
+func TestScrapeFeedsStoresPosts(t *testing.T) {
+	s, db := newTestState(t)
+	user := addUser(t, s, "bob")
+	now := time.Now()
+	server := serveFeed(t,
+		testItem{Title: "Newer", PubDate: now.Add(-time.Hour)},
+		testItem{Title: "Older", PubDate: now.Add(-2 * time.Hour)},
+	)
+	feed := addTestFeed(t, s, user, server)
+
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+
+	if titles := postsFor(t, s, user); !slices.Equal(titles, []string{"Newer", "Older"}) {
+		t.Errorf("expected both posts newest first, got %v", titles)
+	}
+
+	fetch := lastFetch(t, s, feed)
+	if fetch.HttpStatus.Int32 != http.StatusOK || fetch.ItemsSeen != 2 || fetch.ItemsInserted != 2 || fetch.Error.Valid {
+		t.Errorf("fetch was recorded as %+v", fetch)
+	}
+
+	feed = getFeed(t, s, server.URL)
+	if !feed.LastFetchedAt.Valid || feed.FetchLeaseUntil.Valid {
+		t.Errorf("the claim should be released with the fetch time, got %+v", feed)
+	}
+	if !feed.LastBodyHash.Valid {
+		t.Error("the body hash should be remembered")
+	}
+
+	notified := slices.ContainsFunc(db.Notifications(), func(n database.NotifyParams) bool {
+		return n.Channel == postsAddedChannel && n.Payload == feed.ID.String()
+	})
+	if !notified {
+		t.Errorf("watchers should be told about the new posts, got %+v", db.Notifications())
+	}
+}
+
+func TestScrapeFeedsOnlyStoresNewItems(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	now := time.Now()
+	first := testItem{Title: "First", PubDate: now.Add(-2 * time.Hour)}
+	server := serveFeed(t, first)
+	feed := addTestFeed(t, s, user, server)
+
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("first scrape failed: %v", err)
+	}
+
+	// The same body again isn't parsed at all
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("second scrape failed: %v", err)
+	}
+	if fetch := lastFetch(t, s, feed); !fetch.Unchanged || fetch.ItemsInserted != 0 {
+		t.Errorf("an identical body should be skipped, got %+v", fetch)
+	}
+
+	server.Body = rssBody(testItem{Title: "Second", PubDate: now.Add(-time.Hour)}, first)
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("third scrape failed: %v", err)
+	}
+	if fetch := lastFetch(t, s, feed); fetch.Unchanged || fetch.ItemsSeen != 2 || fetch.ItemsInserted != 1 {
+		t.Errorf("only the new item should be stored, got %+v", fetch)
+	}
+	if titles := postsFor(t, s, user); !slices.Equal(titles, []string{"Second", "First"}) {
+		t.Errorf("expected both posts, got %v", titles)
+	}
+}
+
+func TestScrapeFeedsRecordsFailedFetch(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	server := serveFeed(t, testItem{Title: "First", PubDate: time.Now()})
+	server.Status = http.StatusNotFound
+	feed := addTestFeed(t, s, user, server)
+
+	if err := scrapeFeeds(s.Ctx, s); err == nil {
+		t.Fatal("scraping a feed that 404s should fail")
+	}
+
+	fetch := lastFetch(t, s, feed)
+	if fetch.HttpStatus.Int32 != http.StatusNotFound || !fetch.Error.Valid || fetch.ItemsInserted != 0 {
+		t.Errorf("the failed fetch was recorded as %+v", fetch)
+	}
+	if titles := postsFor(t, s, user); len(titles) != 0 {
+		t.Errorf("nothing should be stored, got %v", titles)
+	}
+	if feed := getFeed(t, s, server.URL); feed.FetchLeaseUntil.Valid || feed.LastBodyHash.Valid {
+		t.Errorf("the claim should be released without remembering the body, got %+v", feed)
+	}
+}
+
+func TestScrapeFeedsRetriesUnparseableBody(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	server := serveFeed(t)
+	server.Body = "<rss><channel>"
+	feed := addTestFeed(t, s, user, server)
+
+	if err := scrapeFeeds(s.Ctx, s); err == nil {
+		t.Fatal("scraping a feed that doesn't parse should fail")
+	}
+	if feed := getFeed(t, s, server.URL); feed.LastBodyHash.Valid {
+		t.Error("a body that failed to parse shouldn't be remembered")
+	}
+
+	// Once the feed is fixed its posts come through
+	server.Body = rssBody(testItem{Title: "First", PubDate: time.Now()})
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrape after fixing the feed failed: %v", err)
+	}
+	if fetch := lastFetch(t, s, feed); fetch.ItemsInserted != 1 {
+		t.Errorf("the fixed feed should be stored, got %+v", fetch)
+	}
+}
+
+func TestScrapeFeedsWithoutFeeds(t *testing.T) {
+	s, _ := newTestState(t)
+
+	if err := scrapeFeeds(s.Ctx, s); err == nil {
+		t.Error("scraping without any feeds should fail")
+	}
+}
+
+func TestScrapeFeedsFirstFetchPolicy(t *testing.T) {
+	now := time.Now()
+	items := []testItem{
+		{Title: "Newest", PubDate: now.Add(-time.Hour)},
+		{Title: "Middle", PubDate: now.Add(-3 * 24 * time.Hour)},
+		{Title: "Oldest", PubDate: now.Add(-10 * 24 * time.Hour)},
+	}
+
+	tests := []struct {
+		policy string
+		titles []string
+		unread int
+	}{
+		{policy: "all", titles: []string{"Newest", "Middle", "Oldest"}, unread: 3},
+		{policy: "latest:2", titles: []string{"Newest", "Middle"}, unread: 2},
+		{policy: "days:7", titles: []string{"Newest", "Middle"}, unread: 2},
+		{policy: "mark_read", titles: []string{"Newest", "Middle", "Oldest"}, unread: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			s, _ := newTestState(t)
+			user := addUser(t, s, "bob")
+			server := serveFeed(t, items...)
+			addTestFeed(t, s, user, server, "--first-fetch", tt.policy)
+
+			if err := scrapeFeeds(s.Ctx, s); err != nil {
+				t.Fatalf("scrapeFeeds failed: %v", err)
+			}
+
+			if titles := postsFor(t, s, user); !slices.Equal(titles, tt.titles) {
+				t.Errorf("expected %v, got %v", tt.titles, titles)
+			}
+			follows, _ := s.Db.GetFeedFollowsForUser(s.Ctx, user.ID)
+			if len(follows) != 1 || follows[0].Unread != int64(tt.unread) {
+				t.Errorf("expected %d unread posts, got %+v", tt.unread, follows)
+			}
+		})
+	}
+}
+
+func TestScrapeFeedsFirstFetchPolicyAfterFailedFetch(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	now := time.Now()
+	server := serveFeed(t,
+		testItem{Title: "Newest", PubDate: now.Add(-time.Hour)},
+		testItem{Title: "Middle", PubDate: now.Add(-2 * time.Hour)},
+		testItem{Title: "Oldest", PubDate: now.Add(-3 * time.Hour)},
+	)
+	server.Status = http.StatusNotFound
+	addTestFeed(t, s, user, server, "--first-fetch", "latest:1")
+
+	if err := scrapeFeeds(s.Ctx, s); err == nil {
+		t.Fatal("scraping a feed that 404s should fail")
+	}
+	if feed := getFeed(t, s, server.URL); !feed.LastFetchedAt.Valid || feed.FirstFetchedAt.Valid {
+		t.Fatalf("a failed fetch only counts as an attempt, got %+v", feed)
+	}
+
+	// The first successful fetch still only imports the latest item
+	server.Status = http.StatusOK
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+	if titles := postsFor(t, s, user); !slices.Equal(titles, []string{"Newest"}) {
+		t.Errorf("expected only the latest post, got %v", titles)
+	}
+	if feed := getFeed(t, s, server.URL); !feed.FirstFetchedAt.Valid {
+		t.Errorf("the first successful fetch should be remembered, got %+v", feed)
+	}
+}
+
+func TestClaimSkipsLeasedFeed(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	feed := addTestFeed(t, s, user, serveFeed(t))
+
+	claimed, err := s.Db.ClaimFeed(s.Ctx, database.ClaimFeedParams{Now: time.Now(), LeaseSeconds: 60, ID: feed.ID})
+	if err != nil {
+		t.Fatalf("claiming a free feed failed: %v", err)
+	}
+	if !claimed.FetchLeaseUntil.Valid {
+		t.Fatalf("the claimed feed should have a lease, got %+v", claimed)
+	}
+
+	// The lease is checked against the database clock, a client clock running ahead doesn't expire it
+	later := time.Now().Add(time.Hour)
+	_, err = s.Db.ClaimFeed(s.Ctx, database.ClaimFeedParams{Now: later, LeaseSeconds: 60, ID: feed.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ClaimFeed on a leased feed should return no row, got %v", err)
+	}
+	_, err = s.Db.ClaimDueFeed(s.Ctx, database.ClaimDueFeedParams{
+		Now:          later,
+		LeaseSeconds: 60,
+		DueBefore:    sql.NullTime{Time: later, Valid: true},
+	})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ClaimDueFeed on a leased feed should return no row, got %v", err)
+	}
+	_, err = s.Db.ClaimNextFeedToFetch(s.Ctx, database.ClaimNextFeedToFetchParams{
+		Now:          later,
+		LeaseSeconds: 60,
+		PushedBefore: sql.NullTime{Time: later, Valid: true},
+	})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ClaimNextFeedToFetch on a leased feed should return no row, got %v", err)
+	}
+
+	// A lease that already ran out doesn't hold the feed
+	err = s.Db.ReleaseFeedLease(s.Ctx, database.ReleaseFeedLeaseParams{ID: feed.ID, LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Db.ClaimFeed(s.Ctx, database.ClaimFeedParams{Now: time.Now(), LeaseSeconds: -1, ID: feed.ID}); err != nil {
+		t.Fatalf("claiming a released feed failed: %v", err)
+	}
+	if _, err := s.Db.ClaimFeed(s.Ctx, database.ClaimFeedParams{Now: time.Now(), LeaseSeconds: 60, ID: feed.ID}); err != nil {
+		t.Errorf("an expired lease shouldn't stop a claim, got %v", err)
+	}
+}
+
+func TestAggOnceFetchesDueFeedsOnce(t *testing.T) {
+	s, _ := newTestState(t)
+	s.Config.Agg.Concurrency = 4
+	user := addUser(t, s, "bob")
+	now := time.Now()
+
+	// Fetched just now, so not due within the interval
+	fresh := serveFeed(t, testItem{Title: "Fresh", PubDate: now})
+	addTestFeed(t, s, user, fresh)
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+	fresh.Requests.Store(0)
+
+	var due []*feedServer
+	for i := 0; i < 6; i++ {
+		server := serveFeed(t, testItem{Title: fmt.Sprintf("Post %d", i), PubDate: now})
+		addTestFeed(t, s, user, server)
+		due = append(due, server)
+	}
+
+	if err := HandlerAgg(s, commands.Command{Name: "agg", Args: []string{"1h", "--once"}}); err != nil {
+		t.Fatalf("agg --once failed: %v", err)
+	}
+	for i, server := range due {
+		if got := server.Requests.Load(); got != 1 {
+			t.Errorf("due feed %d: expected one request, got %d", i, got)
+		}
+	}
+	if got := fresh.Requests.Load(); got != 0 {
+		t.Errorf("a feed fetched within the interval shouldn't be fetched, got %d requests", got)
+	}
+	if posts := postsFor(t, s, user); len(posts) != 7 {
+		t.Errorf("expected the posts of every feed, got %v", posts)
+	}
+
+	// Without an interval every feed is due, still only once
+	if err := HandlerAgg(s, commands.Command{Name: "agg", Args: []string{"--once"}}); err != nil {
+		t.Fatalf("agg --once failed: %v", err)
+	}
+	for i, server := range due {
+		if got := server.Requests.Load(); got != 2 {
+			t.Errorf("due feed %d: expected two requests, got %d", i, got)
+		}
+	}
+	if got := fresh.Requests.Load(); got != 1 {
+		t.Errorf("the fresh feed: expected one request, got %d", got)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// middleware auth handles user
+func HandlerStar(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s starring posts: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("star expects the id (as shown by browse) or url of one or more posts: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	for _, ref := range cmd.Args {
+		post, err := findPost(ctx, s, user, ref)
+		if err != nil {
+			s.LogError("Could not star %s: %v", ref, err)
+			return err
+		}
+
+		err = s.Db.StarPost(ctx, database.StarPostParams{
+			UserID:    user.ID,
+			PostID:    post.ID,
+			StarredAt: time.Now(),
+		})
+		if err != nil {
+			s.LogError("Could not star %s: %v", post.Title, err)
+			return err
+		}
+		s.LogInfo("Starred: %s", post.Title)
+	}
+
+	return nil
+}
+
+// middleware auth handles user
+func HandlerUnstar(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s unstarring posts: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("unstar expects the id (as shown by starred) or url of one or more posts: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	for _, ref := range cmd.Args {
+		post, err := findPost(ctx, s, user, ref)
+		if err != nil {
+			s.LogError("Could not unstar %s: %v", ref, err)
+			return err
+		}
+
+		err = s.Db.UnstarPost(ctx, database.UnstarPostParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		if err != nil {
+			s.LogError("Could not unstar %s: %v", post.Title, err)
+			return err
+		}
+		s.LogInfo("Unstarred: %s", post.Title)
+	}
+
+	return nil
+}
+
+// middleware auth handles user
+func HandlerStarred(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("Listing starred posts of user %s", user.Name)
+
+	ctx := s.Ctx
+	posts, err := s.Db.GetStarredPostsForUser(ctx, user.ID)
+	if err != nil {
+		s.LogError("Could not retrieve starred posts: %v", err)
+		return err
+	}
+
+	if len(posts) == 0 {
+		s.LogInfo("No starred posts. Star one with star <post> using the id shown by browse.")
+		return nil
+	}
+
+	s.LogInfo("%s has starred %d posts:", user.Name, len(posts))
+	for _, post := range posts {
+		s.LogInfo("%s "+ColorGreen+"Title:"+ColorReset+" %v | "+ColorGreen+"Feed:"+ColorReset+" %v ("+ColorGreen+"Starred:"+ColorReset+" %v)",
+			shortID(post.ID), post.Title, post.FeedName, post.StarredAt.Format("Jan 02, 2006"))
+		s.LogInfo("    %v", post.Url)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// starred runs the starred command and returns the titles it printed, in order
+func starred(t *testing.T, s *config.State, user database.User) []string {
+	t.Helper()
+
+	out, err := logged(t, s, func() error {
+		return HandlerStarred(s, commands.Command{Name: "starred"}, user)
+	})
+	if err != nil {
+		t.Fatalf("starred failed: %v", err)
+	}
+
+	var titles []string
+	for _, match := range browsedTitle.FindAllStringSubmatch(out, -1) {
+		titles = append(titles, match[1])
+	}
+	return titles
+}
+
+func TestStarAndUnstar(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	now := time.Now()
+	feed := addFetchedFeed(t, s, bob, "Blog",
+		testItem{Title: "First", PubDate: now.Add(-time.Hour)},
+		testItem{Title: "Second", PubDate: now.Add(-2 * time.Hour)},
+		testItem{Title: "Third", PubDate: now.Add(-3 * time.Hour)},
+	)
+	if err := HandlerFollowFeed(s, commands.Command{Name: "follow", Args: []string{feed.Url}}, alice); err != nil {
+		t.Fatalf("follow failed: %v", err)
+	}
+
+	star := func(user database.User, args ...string) error {
+		return HandlerStar(s, commands.Command{Name: "star", Args: args}, user)
+	}
+	if titles := starred(t, s, bob); len(titles) != 0 {
+		t.Fatalf("expected no starred posts, got %v", titles)
+	}
+
+	// Posts can be referred to by url or by the id browse shows, starring twice is fine
+	if err := star(bob, "https://example.com/third", postID(t, s, bob, "First")); err != nil {
+		t.Fatalf("star failed: %v", err)
+	}
+	if err := star(bob, "https://example.com/third"); err != nil {
+		t.Fatalf("starring a starred post failed: %v", err)
+	}
+	titles := starred(t, s, bob)
+	slices.Sort(titles)
+	if !slices.Equal(titles, []string{"First", "Third"}) {
+		t.Errorf("expected First and Third starred, got %v", titles)
+	}
+	if titles := starred(t, s, alice); len(titles) != 0 {
+		t.Errorf("posts bob starred shouldn't be starred for alice, got %v", titles)
+	}
+
+	if err := HandlerUnstar(s, commands.Command{Name: "unstar", Args: []string{"https://example.com/third"}}, bob); err != nil {
+		t.Fatalf("unstar failed: %v", err)
+	}
+	if titles := starred(t, s, bob); !slices.Equal(titles, []string{"First"}) {
+		t.Errorf("expected only First starred, got %v", titles)
+	}
+	// Unstarring a post that isn't starred leaves the others alone
+	if err := HandlerUnstar(s, commands.Command{Name: "unstar", Args: []string{"https://example.com/second"}}, bob); err != nil {
+		t.Fatalf("unstarring an unstarred post failed: %v", err)
+	}
+	if titles := starred(t, s, bob); !slices.Equal(titles, []string{"First"}) {
+		t.Errorf("expected First to stay starred, got %v", titles)
+	}
+}
+
+func TestStarRejectsUnknownPosts(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	addFetchedFeed(t, s, bob, "Blog", testItem{Title: "First", PubDate: time.Now()})
+
+	for _, args := range [][]string{{}, {"https://example.com/missing"}, {"%"}} {
+		if err := HandlerStar(s, commands.Command{Name: "star", Args: args}, bob); err == nil {
+			t.Errorf("star %v should have failed", args)
+		}
+		if err := HandlerUnstar(s, commands.Command{Name: "unstar", Args: args}, bob); err == nil {
+			t.Errorf("unstar %v should have failed", args)
+		}
+	}
+
+	// Only posts of feeds you follow can be starred
+	if err := HandlerStar(s, commands.Command{Name: "star", Args: []string{"https://example.com/first"}}, alice); err == nil {
+		t.Error("alice shouldn't be able to star a post of a feed she doesn't follow")
+	}
+	if titles := starred(t, s, bob); len(titles) != 0 {
+		t.Errorf("failed stars shouldn't star anything, got %v", titles)
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+const (
+	// How far back feedstatus looks when summarising fetches
+	feedStatusWindow = 7 * 24 * time.Hour
+	// How many individual fetches feedstatus lists for a single feed
+	feedStatusRecent = 10
+)
+
+func HandlerFeedStatus(s *config.State, cmd commands.Command) error {
+	s.LogDebug("Getting feed fetch status: args=%v", cmd.Args)
+
+	ctx := s.Ctx
+	stats, err := s.Db.GetFeedFetchStats(ctx, time.Now().Add(-feedStatusWindow))
+	if err != nil {
+		s.LogError("Failed to query feed fetch statistics: %v", err)
+		return err
+	}
+
+	// Narrow down to a single feed when one is passed by url or name
+	if len(cmd.Args) > 0 {
+		var selected []database.GetFeedFetchStatsRow
+		for _, stat := range stats {
+			if stat.Url == cmd.Args[0] || stat.Name == cmd.Args[0] {
+				selected = append(selected, stat)
+			}
+		}
+		if len(selected) == 0 {
+			s.LogError("No feed registered with url or name: %s", cmd.Args[0])
+			return fmt.Errorf("no feed has that url or name, %v", cmd.Args[0])
+		}
+		stats = selected
+	}
+
+	if len(stats) == 0 {
+		s.LogInfo("No feeds found in database")
+		return nil
+	}
+
+	s.LogInfo("Fetches over the last %v:", feedStatusWindow)
+	s.LogInfo("%-20s %8s %8s %10s %10s %10s  %s", "Feed", "Fetches", "Success", "Unchanged", "Avg", "Max", "Last fetched")
+	for _, stat := range stats {
+		successRate := "-"
+		if stat.Fetches > 0 {
+			successRate = fmt.Sprintf("%.0f%%", float64(stat.Successes)/float64(stat.Fetches)*100)
+		}
+
+		lastFetched := "never"
+		if stat.LastFetchedAt.Valid {
+			lastFetched = stat.LastFetchedAt.Time.Format("Jan 02, 2006 at 15:04")
+		}
+
+		s.LogInfo("%-20s %8d %8s %10d %10v %10v  %s",
+			stat.Name,
+			stat.Fetches,
+			successRate,
+			stat.Unchanged,
+			(time.Duration(stat.AvgDurationMs) * time.Millisecond).Round(time.Millisecond),
+			time.Duration(stat.MaxDurationMs)*time.Millisecond,
+			lastFetched)
+	}
+
+	if len(cmd.Args) == 0 {
+		return nil
+	}
+
+	// List the most recent attempts for the selected feed
+	for _, stat := range stats {
+		fetches, err := s.Db.GetRecentFeedFetches(ctx, database.GetRecentFeedFetchesParams{
+			FeedID: stat.ID,
+			Limit:  feedStatusRecent,
+		})
+		if err != nil {
+			s.LogError("Failed to query recent fetches for %s: %v", stat.Name, err)
+			return err
+		}
+
+		s.LogInfo("")
+		s.LogInfo("Recent fetches of %s (%s):", stat.Name, stat.Url)
+		for _, fetch := range fetches {
+			status := "-"
+			if fetch.HttpStatus.Valid {
+				status = fmt.Sprintf("%d", fetch.HttpStatus.Int32)
+			}
+
+			outcome := ColorGreen + "ok" + ColorReset
+			if fetch.Error.Valid {
+				outcome = ColorRed + fetch.Error.String + ColorReset
+			} else if fetch.Unchanged {
+				outcome = "unchanged"
+			}
+
+			s.LogInfo("%s  status: %-4s %8v  %8d bytes  %3d items  %3d new  %s",
+				fetch.StartedAt.Format("Jan 02, 2006 at 15:04:05"),
+				status,
+				time.Duration(fetch.DurationMs)*time.Millisecond,
+				fetch.Bytes,
+				fetch.ItemsSeen,
+				fetch.ItemsInserted,
+				outcome)
+		}
+	}
+
+	return nil
+}
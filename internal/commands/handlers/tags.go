@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// middleware auth handles user
+func HandlerTag(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s tagging a follow: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) < 2 {
+		return fmt.Errorf("tag expects the url or name of a feed you follow and one or more tags: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	follow, err := findFollow(ctx, s, user, cmd.Args[0])
+	if err != nil {
+		s.LogError("Could not tag %s: %v", cmd.Args[0], err)
+		return err
+	}
+
+	for _, arg := range cmd.Args[1:] {
+		tag := normalizeTag(arg)
+		if tag == "" {
+			return fmt.Errorf("tags can't be empty")
+		}
+
+		err = s.Db.TagFollow(ctx, database.TagFollowParams{
+			UserID: user.ID,
+			FeedID: follow.FeedID,
+			Tag:    tag,
+		})
+		if err != nil {
+			s.LogError("Could not tag %s with %s: %v", follow.FeedName, tag, err)
+			return err
+		}
+		s.LogInfo("Tagged %s with %s", follow.FeedName, tag)
+	}
+
+	return nil
+}
+
+// middleware auth handles user
+func HandlerUntag(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s untagging a follow: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) < 2 {
+		return fmt.Errorf("untag expects the url or name of a feed you follow and one or more tags: %v", cmd.Args)
+	}
+
+	ctx := s.Ctx
+	follow, err := findFollow(ctx, s, user, cmd.Args[0])
+	if err != nil {
+		s.LogError("Could not untag %s: %v", cmd.Args[0], err)
+		return err
+	}
+
+	for _, arg := range cmd.Args[1:] {
+		tag := normalizeTag(arg)
+		removed, err := s.Db.UntagFollow(ctx, database.UntagFollowParams{
+			UserID: user.ID,
+			FeedID: follow.FeedID,
+			Tag:    tag,
+		})
+		if err != nil {
+			s.LogError("Could not remove tag %s from %s: %v", tag, follow.FeedName, err)
+			return err
+		}
+		if removed == 0 {
+			s.LogInfo("%s isn't tagged with %s", follow.FeedName, tag)
+			continue
+		}
+		s.LogInfo("Removed tag %s from %s", tag, follow.FeedName)
+	}
+
+	return nil
+}
+
+// findFollow looks up the follow of a feed the user follows by the feed's url or name
+func findFollow(ctx context.Context, s *config.State, user database.User, ref string) (database.GetFeedFollowsForUserRow, error) {
+	feeds, err := s.Db.GetFeedsByUrlOrName(ctx, ref)
+	if err != nil {
+		return database.GetFeedFollowsForUserRow{}, err
+	}
+	follows, err := s.Db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return database.GetFeedFollowsForUserRow{}, err
+	}
+
+	var found []database.GetFeedFollowsForUserRow
+	for _, feed := range feeds {
+		for _, follow := range follows {
+			if follow.FeedID == feed.ID {
+				found = append(found, follow)
+				break
+			}
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return database.GetFeedFollowsForUserRow{}, fmt.Errorf("you don't follow a feed with the url or name %q", ref)
+	case 1:
+		return found[0], nil
+	default:
+		return database.GetFeedFollowsForUserRow{}, fmt.Errorf("you follow several feeds named %q, pass the url instead", ref)
+	}
+}
+
+// Tags are matched case-insensitively, so Kubernetes and kubernetes are the same folder
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// following runs the following command and returns the lines it printed
+func following(t *testing.T, s *config.State, user database.User) []string {
+	t.Helper()
+
+	out, err := logged(t, s, func() error {
+		return HandlerGetFollowing(s, commands.Command{Name: "following"}, user)
+	})
+	if err != nil {
+		t.Fatalf("following failed: %v", err)
+	}
+	out = strings.ReplaceAll(strings.ReplaceAll(out, ColorGreen, ""), ColorReset, "")
+
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if printed, ok := strings.CutPrefix(line, "[INFO] "); ok {
+			lines = append(lines, printed)
+		}
+	}
+	return lines
+}
+
+func TestTagGroupsFollows(t *testing.T) {
+	s, _ := newTestState(t)
+	user := addUser(t, s, "bob")
+	now := time.Now()
+	addFetchedFeed(t, s, user, "Blog", testItem{Title: "Blog post", PubDate: now.Add(-time.Hour)})
+	addFetchedFeed(t, s, user, "News", testItem{Title: "News post", PubDate: now.Add(-2 * time.Hour)})
+	addFetchedFeed(t, s, user, "Podcast", testItem{Title: "Podcast episode", PubDate: now.Add(-3 * time.Hour)})
+
+	tag := func(args ...string) error {
+		return HandlerTag(s, commands.Command{Name: "tag", Args: args}, user)
+	}
+	// Tags are case-insensitive and tagging twice is fine
+	if err := tag("Blog", "Kubernetes", "reading"); err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+	if err := tag("News", "kubernetes"); err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+	if err := tag("News", " KUBERNETES "); err != nil {
+		t.Fatalf("tagging with an existing tag failed: %v", err)
+	}
+
+	want := []string{
+		"kubernetes:",
+		"    Blog (1 unread)",
+		"    News (1 unread)",
+		"reading:",
+		"    Blog (1 unread)",
+		"untagged:",
+		"    Podcast (1 unread)",
+	}
+	if lines := following(t, s, user); !slices.Equal(lines, want) {
+		t.Errorf("expected follows grouped by folder:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+	}
+
+	if titles := browse(t, s, user, "10", "--folder", "Kubernetes"); !slices.Equal(titles, []string{"Blog post", "News post"}) {
+		t.Errorf("expected the posts of the kubernetes folder, got %v", titles)
+	}
+	if titles := browse(t, s, user, "--folder", "kubernetes", "1"); !slices.Equal(titles, []string{"Blog post"}) {
+		t.Errorf("the limit should apply to the folder, got %v", titles)
+	}
+	if titles := browse(t, s, user, "10", "--folder", "missing"); len(titles) != 0 {
+		t.Errorf("a folder without feeds should have no posts, got %v", titles)
+	}
+
+	if err := HandlerUntag(s, commands.Command{Name: "untag", Args: []string{"Blog", "kubernetes", "unknown"}}, user); err != nil {
+		t.Fatalf("untag failed: %v", err)
+	}
+	if titles := browse(t, s, user, "10", "--folder", "kubernetes"); !slices.Equal(titles, []string{"News post"}) {
+		t.Errorf("expected only News left in the kubernetes folder, got %v", titles)
+	}
+	if titles := browse(t, s, user, "10", "--folder", "reading"); !slices.Equal(titles, []string{"Blog post"}) {
+		t.Errorf("untagging shouldn't remove the other tags of Blog, got %v", titles)
+	}
+}
+
+func TestTagRejectsInvalidArguments(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	addFetchedFeed(t, s, bob, "Blog", testItem{Title: "Blog post", PubDate: time.Now()})
+
+	for _, args := range [][]string{{}, {"Blog"}, {"Missing", "reading"}, {"Blog", " "}} {
+		if err := HandlerTag(s, commands.Command{Name: "tag", Args: args}, bob); err == nil {
+			t.Errorf("tag %v should have failed", args)
+		}
+	}
+	for _, args := range [][]string{{}, {"Blog"}, {"Missing", "reading"}} {
+		if err := HandlerUntag(s, commands.Command{Name: "untag", Args: args}, bob); err == nil {
+			t.Errorf("untag %v should have failed", args)
+		}
+	}
+
+	// Only feeds you follow can be tagged
+	if err := HandlerTag(s, commands.Command{Name: "tag", Args: []string{"Blog", "reading"}}, alice); err == nil {
+		t.Error("alice shouldn't be able to tag a feed she doesn't follow")
+	}
+	if err := HandlerBrowse(s, commands.Command{Name: "browse", Args: []string{"--folder"}}, bob); err == nil {
+		t.Error("browse --folder without a tag should have failed")
+	}
+	if lines := following(t, s, bob); !slices.Equal(lines, []string{"bob is following: Blog (1 unread)"}) {
+		t.Errorf("failed tags shouldn't tag anything, got %v", lines)
+	}
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -10,7 +10,6 @@ import (
 	"github.com/git-cst/bootdev_gator/internal/commands"
 	"github.com/git-cst/bootdev_gator/internal/config"
 	"github.com/git-cst/bootdev_gator/internal/database"
-	"github.com/lib/pq"
 )
 
 func HandlerLogin(s *config.State, cmd commands.Command) error {
@@ -22,7 +21,7 @@ func HandlerLogin(s *config.State, cmd commands.Command) error {
 
 	loginUserRequest := cmd.Args[0]
 	s.LogDebug("User attempting login: %s", loginUserRequest)
-	databaseUser, err := s.Db.GetUser(context.Background(), loginUserRequest)
+	databaseUser, err := s.Db.GetUser(s.Ctx, loginUserRequest)
 	if err != nil {
 		// Check if this is a no data found error
 		if err == sql.ErrNoRows {
@@ -53,14 +52,12 @@ func HandlerRegister(s *config.State, cmd commands.Command) error {
 	}
 
 	s.LogInfo("Attempting to create user: %s", registerUser)
-	createdUser, err := s.Db.CreateUser(context.Background(), params)
+	createdUser, err := s.Db.CreateUser(s.Ctx, params)
 	if err != nil {
 		// Check if this is a unique constraint violation
-		if pqErr, ok := err.(*pq.Error); ok {
-			if pqErr.Code == "23505" { // unique_violation code
-				s.LogInfo("User %s already exists", registerUser)
-				os.Exit(1)
-			}
+		if database.IsUniqueViolation(err) {
+			s.LogInfo("User %s already exists", registerUser)
+			os.Exit(1)
 		}
 		// Handle other errors
 		s.LogError("Error creating user %s in database, error was %v", registerUser, err)
@@ -74,7 +71,7 @@ func HandlerRegister(s *config.State, cmd commands.Command) error {
 
 func HandlerUsers(s *config.State, cmd commands.Command) error {
 	s.LogDebug("Retrieving users")
-	users, err := s.Db.GetUsers(context.Background())
+	users, err := s.Db.GetUsers(s.Ctx)
 	if err != nil {
 		// Check if this is a no data found error
 		if err == sql.ErrNoRows {
@@ -87,6 +84,9 @@ func HandlerUsers(s *config.State, cmd commands.Command) error {
 
 	for _, user := range users {
 		message := fmt.Sprintf("* %s", user.Name)
+		if user.IsAdmin {
+			message += " (admin)"
+		}
 		if user.Name == s.Config.User {
 			message += " (current)"
 		}
@@ -98,9 +98,63 @@ func HandlerUsers(s *config.State, cmd commands.Command) error {
 	return nil
 }
 
+// middleware auth handles user
+func HandlerAdmin(s *config.State, cmd commands.Command, user database.User) error {
+	s.LogDebug("User %s managing admins: args=%v", user.Name, cmd.Args)
+	if len(cmd.Args) != 2 || (cmd.Args[0] != "grant" && cmd.Args[0] != "revoke") {
+		return fmt.Errorf("admin expects grant or revoke and the name of a user: %v", cmd.Args)
+	}
+	if !user.IsAdmin {
+		s.LogError("User %s tried to %s admin rights without being an admin", user.Name, cmd.Args[0])
+		return fmt.Errorf("only admins can grant or revoke admin rights")
+	}
+
+	ctx := s.Ctx
+	grant := cmd.Args[0] == "grant"
+	name := cmd.Args[1]
+	err := s.Db.InTx(ctx, func(q database.Querier) error {
+		target, err := q.GetUser(ctx, name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("no user is named %s", name)
+			}
+			return err
+		}
+
+		// Someone has to be left to grant admin rights
+		if !grant && target.IsAdmin {
+			admins, err := q.CountAdmins(ctx)
+			if err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return fmt.Errorf("%s is the last admin, grant someone else admin rights first", name)
+			}
+		}
+
+		_, err = q.SetUserAdmin(ctx, database.SetUserAdminParams{
+			IsAdmin:   grant,
+			UpdatedAt: time.Now(),
+			Name:      name,
+		})
+		return err
+	})
+	if err != nil {
+		s.LogError("Could not %s admin rights for %s: %v", cmd.Args[0], name, err)
+		return err
+	}
+
+	if grant {
+		s.LogInfo("%s is now an admin", name)
+	} else {
+		s.LogInfo("%s is no longer an admin", name)
+	}
+	return nil
+}
+
 func HandlerReset(s *config.State, cmd commands.Command) error {
 	s.LogDebug("Starting reset users process")
-	err := s.Db.ResetUsers(context.Background())
+	err := s.Db.ResetUsers(s.Ctx)
 	if err != nil {
 		s.LogError("Error resetting user table: %v", err)
 		os.Exit(1)
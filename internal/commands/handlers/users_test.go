@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// isAdmin reports whether the user with name has admin rights in the database
+func isAdmin(t *testing.T, s *config.State, name string) bool {
+	t.Helper()
+
+	user, err := s.Db.GetUser(s.Ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.IsAdmin
+}
+
+func TestFirstUserIsAdmin(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+
+	if !bob.IsAdmin || alice.IsAdmin {
+		t.Errorf("only the first user should be an admin, got bob %v and alice %v", bob.IsAdmin, alice.IsAdmin)
+	}
+}
+
+func TestAdminGrantAndRevoke(t *testing.T) {
+	s, _ := newTestState(t)
+	bob := addUser(t, s, "bob")
+	alice := addUser(t, s, "alice")
+	addUser(t, s, "carol")
+
+	admin := func(user database.User, args ...string) error {
+		return HandlerAdmin(s, commands.Command{Name: "admin", Args: args}, user)
+	}
+
+	// Users can't make themselves or others admins
+	if err := admin(alice, "grant", "alice"); err == nil {
+		t.Error("alice shouldn't be able to grant herself admin rights")
+	}
+	if err := admin(alice, "revoke", "bob"); err == nil {
+		t.Error("alice shouldn't be able to revoke bob's admin rights")
+	}
+	if isAdmin(t, s, "alice") || !isAdmin(t, s, "bob") {
+		t.Fatal("refused changes shouldn't change admin rights")
+	}
+
+	for _, args := range [][]string{{}, {"grant"}, {"promote", "alice"}, {"grant", "missing"}} {
+		if err := admin(bob, args...); err == nil {
+			t.Errorf("admin %v should have failed", args)
+		}
+	}
+
+	// The last admin can't step down
+	if err := admin(bob, "revoke", "bob"); err == nil {
+		t.Error("revoking the last admin should have failed")
+	}
+
+	if err := admin(bob, "grant", "alice"); err != nil {
+		t.Fatalf("admin grant failed: %v", err)
+	}
+	if !isAdmin(t, s, "alice") {
+		t.Fatal("alice should be an admin")
+	}
+	if err := admin(bob, "revoke", "bob"); err != nil {
+		t.Fatalf("revoking an admin with another one left failed: %v", err)
+	}
+	if isAdmin(t, s, "bob") {
+		t.Error("bob shouldn't be an admin anymore")
+	}
+	// Revoking rights someone doesn't have is fine
+	alice, err := s.Db.GetUser(s.Ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin(alice, "revoke", "carol"); err != nil {
+		t.Errorf("revoking rights carol doesn't have failed: %v", err)
+	}
+}
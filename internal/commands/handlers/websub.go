@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/config"
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// States a WebSub subscription moves through
+const (
+	webSubPending = "pending"
+	webSubActive  = "active"
+	webSubDenied  = "denied"
+	// The feed is being removed and the hub was asked to stop pushing it
+	webSubUnsubscribing = "unsubscribing"
+)
+
+const (
+	// Pushed bodies larger than this are rejected
+	webSubMaxPushBytes = 10 << 20
+	// A subscription request the hub never verified is sent again after this long
+	webSubRetryPending = time.Hour
+)
+
+// discoverHub finds the WebSub hub and topic of a feed, from the Link header first and the feed's atom links second
+func discoverHub(header http.Header, feed *RSSFeed) (string, string) {
+	var hub, self string
+	for _, link := range parseLinkHeader(header) {
+		if link.Rel == "hub" && hub == "" {
+			hub = link.Href
+		}
+		if link.Rel == "self" && self == "" {
+			self = link.Href
+		}
+	}
+
+	for _, link := range feed.Channel.Links {
+		if link.Rel == "hub" && hub == "" {
+			hub = link.Href
+		}
+		if link.Rel == "self" && self == "" {
+			self = link.Href
+		}
+	}
+
+	return hub, self
+}
+
+// parseLinkHeader splits Link headers such as `<https://hub.example>; rel="hub"` into links
+func parseLinkHeader(header http.Header) []AtomLink {
+	var links []AtomLink
+	for _, value := range header.Values("Link") {
+		for _, part := range strings.Split(value, ",") {
+			segments := strings.Split(part, ";")
+			href := strings.TrimSpace(segments[0])
+			if !strings.HasPrefix(href, "<") || !strings.HasSuffix(href, ">") {
+				continue
+			}
+			href = strings.Trim(href, "<>")
+
+			for _, param := range segments[1:] {
+				key, val, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(key, "rel") {
+					continue
+				}
+				// A link can carry several space separated relations
+				for _, rel := range strings.Fields(strings.Trim(val, `"`)) {
+					links = append(links, AtomLink{Rel: strings.ToLower(rel), Href: href})
+				}
+			}
+		}
+	}
+	return links
+}
+
+// ensureWebSubSubscription subscribes to the hub a feed advertises unless that is already taken care of
+func ensureWebSubSubscription(ctx context.Context, s *config.State, feed database.Feed, hub string, topic string) error {
+	existing, err := s.Db.GetWebSubSubscriptionByFeed(ctx, feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err == nil && existing.HubUrl == hub && existing.TopicUrl == topic {
+		switch existing.State {
+		case webSubActive, webSubDenied:
+			// Active subscriptions are renewed separately and denied ones aren't retried with the same hub
+			return nil
+		case webSubPending:
+			if time.Since(existing.UpdatedAt) < webSubRetryPending {
+				return nil
+			}
+		}
+	}
+
+	secret, err := newWebSubSecret()
+	if err != nil {
+		return err
+	}
+
+	subscription, err := s.Db.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		FeedID:    feed.ID,
+		HubUrl:    hub,
+		TopicUrl:  topic,
+		Secret:    secret,
+		State:     webSubPending,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.LogInfo("Subscribing to WebSub hub %s for feed %s", hub, feed.Name)
+	return requestWebSubSubscription(ctx, s, subscription)
+}
+
+// requestWebSubSubscription asks the hub to (re)subscribe us, the hub confirms later through the callback
+func requestWebSubSubscription(ctx context.Context, s *config.State, subscription database.WebsubSubscription) error {
+	callback, err := s.Config.WebSub.Callback()
+	if err != nil {
+		return err
+	}
+
+	lease, err := s.Config.WebSub.LeaseDuration()
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {subscription.TopicUrl},
+		"hub.callback":      {callback + "/" + subscription.ID.String()},
+		"hub.secret":        {subscription.Secret},
+		"hub.lease_seconds": {strconv.Itoa(int(lease.Seconds()))},
+	}
+	return sendWebSubRequest(ctx, s, subscription.HubUrl, "subscription", form)
+}
+
+// requestWebSubUnsubscription asks the hub to stop pushing a feed, the hub confirms later through the callback
+func requestWebSubUnsubscription(ctx context.Context, s *config.State, subscription database.WebsubSubscription) error {
+	callback, err := s.Config.WebSub.Callback()
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"hub.mode":     {"unsubscribe"},
+		"hub.topic":    {subscription.TopicUrl},
+		"hub.callback": {callback + "/" + subscription.ID.String()},
+	}
+	return sendWebSubRequest(ctx, s, subscription.HubUrl, "unsubscription", form)
+}
+
+// sendWebSubRequest posts a subscription or unsubscription request (kind) to a hub
+func sendWebSubRequest(ctx context.Context, s *config.State, hub string, kind string, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.Config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending %s request to hub: %v", kind, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hub rejected %s request: %s", kind, resp.Status)
+	}
+
+	return nil
+}
+
+// renewWebSubSubscriptions resubscribes every active subscription whose lease ends within margin
+func renewWebSubSubscriptions(ctx context.Context, s *config.State, margin time.Duration) {
+	expiring, err := s.Db.GetWebSubSubscriptionsToRenew(ctx, sql.NullTime{
+		Time:  time.Now().Add(margin),
+		Valid: true,
+	})
+	if err != nil {
+		s.LogError("Failed to query WebSub subscriptions to renew: %v", err)
+		return
+	}
+
+	for _, subscription := range expiring {
+		s.LogInfo("Renewing WebSub subscription to %s for %s", subscription.HubUrl, subscription.TopicUrl)
+		err := requestWebSubSubscription(ctx, s, subscription)
+		if err != nil {
+			s.LogError("Failed to renew WebSub subscription %v: %v", subscription.ID, err)
+		}
+	}
+}
+
+func newWebSubSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// newWebSubServer builds the callback endpoint hubs verify subscriptions with and push content to
+func newWebSubServer(s *config.State) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// The subscription id is the last segment of the callback url
+		subscriptionId, err := uuid.Parse(path.Base(r.URL.Path))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		subscription, err := s.Db.GetWebSubSubscription(r.Context(), subscriptionId)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				s.LogError("Failed to retrieve WebSub subscription %v: %v", subscriptionId, err)
+				http.NotFound(w, r)
+				return
+			}
+			// The subscription went with its feed, so the hub may stop pushing it
+			if r.Method == http.MethodGet && r.URL.Query().Get("hub.mode") == "unsubscribe" {
+				s.LogInfo("WebSub unsubscription from %s confirmed", r.URL.Query().Get("hub.topic"))
+				io.WriteString(w, r.URL.Query().Get("hub.challenge"))
+				return
+			}
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			verifyWebSubIntent(s, w, r, subscription)
+		case http.MethodPost:
+			receiveWebSubContent(s, w, r, subscription)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	return &http.Server{
+		Addr:              s.Config.WebSub.Addr(),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// verifyWebSubIntent answers the hub's challenge for subscriptions we actually asked for
+func verifyWebSubIntent(s *config.State, w http.ResponseWriter, r *http.Request, subscription database.WebsubSubscription) {
+	query := r.URL.Query()
+	if query.Get("hub.topic") != subscription.TopicUrl {
+		s.LogError("WebSub verification for unexpected topic %q on subscription %v", query.Get("hub.topic"), subscription.ID)
+		http.NotFound(w, r)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || leaseSeconds <= 0 {
+			http.Error(w, "invalid hub.lease_seconds", http.StatusBadRequest)
+			return
+		}
+
+		err = s.Db.ActivateWebSubSubscription(r.Context(), database.ActivateWebSubSubscriptionParams{
+			UpdatedAt: time.Now(),
+			LeaseExpiresAt: sql.NullTime{
+				Time:  time.Now().Add(time.Duration(leaseSeconds) * time.Second),
+				Valid: true,
+			},
+			ID: subscription.ID,
+		})
+		if err != nil {
+			s.LogError("Failed to activate WebSub subscription %v: %v", subscription.ID, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		s.LogInfo("WebSub subscription for %s verified, lease of %ds", subscription.TopicUrl, leaseSeconds)
+		io.WriteString(w, query.Get("hub.challenge"))
+	case "denied":
+		err := s.Db.SetWebSubSubscriptionState(r.Context(), database.SetWebSubSubscriptionStateParams{
+			UpdatedAt: time.Now(),
+			State:     webSubDenied,
+			ID:        subscription.ID,
+		})
+		if err != nil {
+			s.LogError("Failed to mark WebSub subscription %v as denied: %v", subscription.ID, err)
+		}
+		s.LogInfo("WebSub hub denied subscription for %s: %s", subscription.TopicUrl, query.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+	case "unsubscribe":
+		// Only unsubscriptions removefeed asked for are confirmed
+		if subscription.State != webSubUnsubscribing {
+			http.NotFound(w, r)
+			return
+		}
+		s.LogInfo("WebSub unsubscription from %s confirmed", subscription.TopicUrl)
+		io.WriteString(w, query.Get("hub.challenge"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// receiveWebSubContent stores the posts a hub pushed to us once the signature checks out
+func receiveWebSubContent(s *config.State, w http.ResponseWriter, r *http.Request, subscription database.WebsubSubscription) {
+	if subscription.State != webSubActive {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webSubMaxPushBytes+1))
+	if err != nil {
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > webSubMaxPushBytes {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Per the spec content with a bad signature is acknowledged but ignored
+	if !validWebSubSignature(r.Header.Get("X-Hub-Signature"), body, subscription.Secret) {
+		s.LogError("Ignoring WebSub push for %s with missing or invalid signature", subscription.TopicUrl)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := s.Db.GetFeedByID(r.Context(), subscription.FeedID)
+	if err != nil {
+		s.LogError("Failed to retrieve feed for WebSub push %v: %v", subscription.ID, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	rssFeed, err := parseFeed(body, time.Now())
+	if err != nil {
+		s.LogError("Could not parse WebSub push for %s: %v", feed.Name, err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	s.LogInfo("Received WebSub push for %v", feed.Name)
+	inserted, err := storePosts(r.Context(), s, s.Db, feed, rssFeed.Channel.Items)
+	if err != nil {
+		// Failing the delivery makes the hub send it again
+		s.LogError("Could not store WebSub push for %s: %v", feed.Name, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if inserted > 0 {
+		notify(r.Context(), s, postsAddedChannel, feed.ID.String())
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validWebSubSignature checks an X-Hub-Signature header of the form `sha256=<hex hmac>`
+func validWebSubSignature(signature string, body []byte, secret string) bool {
+	method, digest, found := strings.Cut(signature, "=")
+	if !found {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
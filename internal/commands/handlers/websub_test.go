@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/internal/commands"
+	"github.com/git-cst/bootdev_gator/internal/database"
+)
+
+// testHub accepts subscription and unsubscription requests and verifies them against the callback like a WebSub hub would
+type testHub struct {
+	*httptest.Server
+	requests chan url.Values
+	// verified gets the answer of the callback to each verification
+	verified chan string
+}
+
+func serveHub(t *testing.T) *testHub {
+	t.Helper()
+
+	hub := &testHub{requests: make(chan url.Values, 1), verified: make(chan string, 1)}
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		form := r.PostForm
+		mode := form.Get("hub.mode")
+		if mode != "subscribe" && mode != "unsubscribe" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		hub.requests <- form
+		w.WriteHeader(http.StatusAccepted)
+
+		// Intent is verified once the request was accepted
+		query := url.Values{
+			"hub.mode":      {mode},
+			"hub.topic":     {form.Get("hub.topic")},
+			"hub.challenge": {"challenge-" + form.Get("hub.topic")},
+		}
+		if mode == "subscribe" {
+			query.Set("hub.lease_seconds", form.Get("hub.lease_seconds"))
+		}
+		go func() {
+			hub.verified <- hub.verify(form.Get("hub.callback"), query)
+		}()
+	}))
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+// verify sends a verification to the callback and returns what it echoed, or its status when it refused
+func (hub *testHub) verify(callback string, query url.Values) string {
+	resp, err := http.Get(callback + "?" + query.Encode())
+	if err != nil {
+		return err.Error()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.Status
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+// push delivers body to the callback signed with secret
+func push(t *testing.T, callback string, body string, secret string) {
+	t.Helper()
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	req, err := http.NewRequest(http.MethodPost, callback, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/rss+xml")
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// Pushes are acknowledged whether or not they are used
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected the push to be accepted, got %s", resp.Status)
+	}
+}
+
+func TestWebSubSubscribeAndPush(t *testing.T) {
+	s, db := newTestState(t)
+	callback := httptest.NewServer(newWebSubServer(s).Handler)
+	t.Cleanup(callback.Close)
+	s.Config.WebSub.CallbackURL = callback.URL + "/websub/"
+
+	hub := serveHub(t)
+	user := addUser(t, s, "bob")
+	server := serveFeed(t, testItem{Title: "Polled", PubDate: time.Now().Add(-time.Hour)})
+	server.Header.Set("Link", "<"+hub.URL+`>; rel="hub", <`+server.URL+`>; rel="self"`)
+	feed := addTestFeed(t, s, user, server)
+
+	// Fetching a feed that advertises a hub subscribes to it
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+	var request url.Values
+	select {
+	case request = <-hub.requests:
+	default:
+		t.Fatal("the hub didn't get a subscription request")
+	}
+	if request.Get("hub.topic") != server.URL || request.Get("hub.secret") == "" {
+		t.Fatalf("unexpected subscription request %v", request)
+	}
+	if !strings.HasPrefix(request.Get("hub.callback"), callback.URL+"/websub/") {
+		t.Fatalf("the callback %q isn't under the configured url", request.Get("hub.callback"))
+	}
+
+	select {
+	case answer := <-hub.verified:
+		if answer != "challenge-"+server.URL {
+			t.Fatalf("the callback should echo the challenge, got %q", answer)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub never verified the subscription")
+	}
+	subscription, err := s.Db.GetWebSubSubscriptionByFeed(s.Ctx, feed.ID)
+	if err != nil || subscription.State != webSubActive || !subscription.LeaseExpiresAt.Valid {
+		t.Fatalf("the verified subscription should be active with a lease, got %+v (%v)", subscription, err)
+	}
+
+	// Verifications for another topic aren't confirmed
+	answer := hub.verify(request.Get("hub.callback"), url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {"https://example.com/other"},
+		"hub.challenge":     {"other"},
+		"hub.lease_seconds": {"60"},
+	})
+	if answer == "other" {
+		t.Error("a verification for another topic shouldn't echo the challenge")
+	}
+
+	before := len(db.Notifications())
+	push(t, request.Get("hub.callback"), rssBody(testItem{Title: "Pushed", PubDate: time.Now()}), request.Get("hub.secret"))
+	if titles := postsFor(t, s, user); !slices.Equal(titles, []string{"Pushed", "Polled"}) {
+		t.Errorf("the signed push should be stored, got %v", titles)
+	}
+	notified := slices.ContainsFunc(db.Notifications()[before:], func(n database.NotifyParams) bool {
+		return n.Channel == postsAddedChannel && n.Payload == feed.ID.String()
+	})
+	if !notified {
+		t.Errorf("watchers should be told about the pushed posts, got %+v", db.Notifications()[before:])
+	}
+
+	push(t, request.Get("hub.callback"), rssBody(testItem{Title: "Forged", PubDate: time.Now()}), "not the secret")
+	if titles := postsFor(t, s, user); slices.Contains(titles, "Forged") {
+		t.Errorf("a push with a bad signature should be ignored, got %v", titles)
+	}
+}
+
+func TestRemoveFeedUnsubscribesFromHub(t *testing.T) {
+	s, _ := newTestState(t)
+	callback := httptest.NewServer(newWebSubServer(s).Handler)
+	t.Cleanup(callback.Close)
+	s.Config.WebSub.CallbackURL = callback.URL + "/websub/"
+
+	hub := serveHub(t)
+	user := addUser(t, s, "bob")
+	server := serveFeed(t, testItem{Title: "Polled", PubDate: time.Now()})
+	server.Header.Set("Link", "<"+hub.URL+`>; rel="hub", <`+server.URL+`>; rel="self"`)
+	addTestFeed(t, s, user, server)
+	if err := scrapeFeeds(s.Ctx, s); err != nil {
+		t.Fatalf("scrapeFeeds failed: %v", err)
+	}
+
+	var subscribed url.Values
+	select {
+	case subscribed = <-hub.requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub didn't get a subscription request")
+	}
+	select {
+	case <-hub.verified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub never verified the subscription")
+	}
+
+	// Unsubscriptions we didn't ask for are refused
+	answer := hub.verify(subscribed.Get("hub.callback"), url.Values{
+		"hub.mode":      {"unsubscribe"},
+		"hub.topic":     {server.URL},
+		"hub.challenge": {"forged"},
+	})
+	if answer == "forged" {
+		t.Fatal("an unsubscription nobody asked for shouldn't be confirmed")
+	}
+
+	if err := HandlerRemoveFeed(s, commands.Command{Name: "removefeed", Args: []string{server.URL}}, user); err != nil {
+		t.Fatalf("removefeed failed: %v", err)
+	}
+	select {
+	case request := <-hub.requests:
+		if request.Get("hub.mode") != "unsubscribe" || request.Get("hub.topic") != server.URL ||
+			request.Get("hub.callback") != subscribed.Get("hub.callback") {
+			t.Errorf("expected an unsubscription of the subscribed callback, got %v", request)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub didn't get an unsubscription request")
+	}
+	select {
+	case answer := <-hub.verified:
+		if answer != "challenge-"+server.URL {
+			t.Errorf("the callback should confirm the unsubscription, got %q", answer)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub never verified the unsubscription")
+	}
+
+	if feeds, _ := s.Db.GetAllFeeds(s.Ctx); len(feeds) != 0 {
+		t.Errorf("the feed should be gone, got %+v", feeds)
+	}
+}
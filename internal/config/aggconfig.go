@@ -0,0 +1,54 @@
+package config
+
+import (
+	"time"
+)
+
+const (
+	DefaultShutdownGrace = 30 * time.Second
+	DefaultConcurrency   = 4
+	DefaultFetchLease    = 5 * time.Minute
+)
+
+// AggConfig holds the settings of the aggregator service
+type AggConfig struct {
+	ShutdownGrace string `json:"shutdown_grace,omitempty"`
+	Concurrency   int    `json:"concurrency,omitempty"`
+	FetchLease    string `json:"fetch_lease,omitempty"`
+	FirstFetch    string `json:"first_fetch,omitempty"`
+	Retention     string `json:"retention,omitempty"`
+	PruneInterval string `json:"prune_interval,omitempty"`
+}
+
+// Grace returns how long in-flight fetches may take to finish once shutdown is requested
+func (a AggConfig) Grace() (time.Duration, error) {
+	return parseDurationSetting("shutdown_grace", a.ShutdownGrace, DefaultShutdownGrace)
+}
+
+// Workers returns how many feeds may be fetched at the same time
+func (a AggConfig) Workers() int {
+	if a.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return a.Concurrency
+}
+
+// Lease returns how long a claimed feed stays reserved for the instance fetching it
+func (a AggConfig) Lease() (time.Duration, error) {
+	return parseDurationSetting("fetch_lease", a.FetchLease, DefaultFetchLease)
+}
+
+// FirstFetchPolicy returns the default policy for the items of a feed's first fetch, feeds can override it
+func (a AggConfig) FirstFetchPolicy() (FirstFetchPolicy, error) {
+	return ParseFirstFetchPolicy(a.FirstFetch)
+}
+
+// RetentionPolicy returns the default policy for pruning the posts of a feed, feeds can override it
+func (a AggConfig) RetentionPolicy() (RetentionPolicy, error) {
+	return ParseRetentionPolicy(a.Retention)
+}
+
+// PruneEvery returns how often a running aggregator prunes posts, 0 when it doesn't
+func (a AggConfig) PruneEvery() (time.Duration, error) {
+	return parseDurationSetting("prune_interval", a.PruneInterval, 0)
+}
@@ -0,0 +1,17 @@
+package config
+
+const DefaultArchiveKeep = 5
+
+// ArchiveConfig controls whether raw feed responses are kept so they can be parsed again later
+type ArchiveConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	Keep    int  `json:"keep,omitempty"`
+}
+
+// KeepPerFeed returns how many responses are archived per feed
+func (a ArchiveConfig) KeepPerFeed() int {
+	if a.Keep <= 0 {
+		return DefaultArchiveKeep
+	}
+	return a.Keep
+}
@@ -1,52 +1,194 @@
 package config
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
 type ClientOptions struct {
-	Timeout     time.Duration
-	UserAgent   string
-	ContentType string
-	Headers     map[string]string
+	Timeout            time.Duration
+	UserAgent          string
+	Accept             string
+	Headers            map[string]string
+	Proxy              func(*http.Request) (*url.URL, error)
+	RootCAs            *x509.CertPool
+	MinTLSVersion      uint16
+	HostMinInterval    time.Duration
+	HostMaxConcurrency int
+	RetryAttempts      int
+	RetryBaseDelay     time.Duration
+	RetryMaxDelay      time.Duration
+	// Logf receives a line for every retried request
+	Logf func(format string, v ...interface{})
 }
 
 type Client struct {
-	Http        http.Client
-	UserAgent   string
-	ContentType string
-	Headers     map[string]string
+	Http      http.Client
+	UserAgent string
+	Accept    string
+	Headers   map[string]string
+	limiter   *hostLimiter
+	retry     retryPolicy
+	logf      func(format string, v ...interface{})
 }
 
 func NewClient(options ClientOptions) Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = options.Proxy
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    options.RootCAs,
+		MinVersion: options.MinTLSVersion,
+	}
+
 	return Client{
 		Http: http.Client{
-			Timeout: options.Timeout,
+			Timeout:       options.Timeout,
+			Transport:     transport,
+			CheckRedirect: checkRedirect,
+		},
+		UserAgent: options.UserAgent,
+		Accept:    options.Accept,
+		Headers:   options.Headers,
+		limiter:   newHostLimiter(options.HostMinInterval, options.HostMaxConcurrency),
+		retry: retryPolicy{
+			attempts:  options.RetryAttempts,
+			baseDelay: options.RetryBaseDelay,
+			maxDelay:  options.RetryMaxDelay,
 		},
-		UserAgent:   options.UserAgent,
-		ContentType: options.ContentType,
-		Headers:     options.Headers,
+		logf: options.Logf,
 	}
 }
 
+type credentialHeadersKey struct{}
+
+// WithCredentialHeaders marks the named headers of requests made with ctx as credentials, which are
+// dropped when a redirect leaves the host they were meant for. Go already does this for Authorization
+// and Cookie, but not for custom headers like X-Api-Key
+func WithCredentialHeaders(ctx context.Context, names ...string) context.Context {
+	return context.WithValue(ctx, credentialHeadersKey{}, names)
+}
+
+// checkRedirect follows up to 10 redirects like the default policy, leaving out credential headers once
+// the redirects went to another host
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	names, _ := req.Context().Value(credentialHeadersKey{}).([]string)
+	if len(names) == 0 {
+		return nil
+	}
+
+	// Every redirect starts from the headers of the first request, so going back to its host doesn't
+	// bring the credentials back either
+	host := via[0].URL.Host
+	leftHost := !strings.EqualFold(req.URL.Host, host)
+	for _, hop := range via[1:] {
+		if !strings.EqualFold(hop.URL.Host, host) {
+			leftHost = true
+		}
+	}
+
+	if leftHost {
+		for _, name := range names {
+			req.Header.Del(name)
+		}
+	}
+	return nil
+}
+
 // Do performs an HTTP request and applies all configured headers
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	// Set the User-Agent if configured
-	if c.UserAgent != "" {
-		req.Header.Set("User-Agent", c.UserAgent)
+	// Headers already set on the request (e.g. per feed auth) take precedence over the client defaults
+	setDefault := func(key string, value string) {
+		if value != "" && req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
 	}
 
-	// Set the Content-Type if configured
-	if c.ContentType != "" {
-		req.Header.Set("Content-Type", c.ContentType)
-	}
+	// Set the User-Agent and Accept headers if configured
+	setDefault("User-Agent", c.UserAgent)
+	setDefault("Accept", c.Accept)
 
 	// Add any additional custom headers
 	for key, value := range c.Headers {
-		req.Header.Set(key, value)
+		setDefault(key, value)
+	}
+
+	// Only requests without a body can safely be sent again
+	attempts := 1
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		attempts = max(c.retry.attempts, 1)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(req)
+		if attempt >= attempts || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		wait := c.retry.delay(attempt, resp)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			// Drain so the connection can be reused, this also frees the host slot
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if c.logf != nil {
+			c.logf("Retrying %s%s in %v (attempt %d of %d): %s", req.URL, describeRequest(req.Context()), wait.Round(time.Millisecond), attempt+1, attempts, reason)
+		}
+
+		err = sleepContext(req.Context(), wait)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send performs a single attempt of the request
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	// Without a limiter (zero value client) just perform the request
+	if c.limiter == nil {
+		return c.Http.Do(req)
+	}
+
+	// Wait for our turn on this host, the slot is held until the body is closed
+	release, err := c.limiter.acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
 	}
 
 	// Use the underlying http.Client to perform the request
-	return c.Http.Do(req)
+	resp, err := c.Http.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody gives the host slot back once the caller is done with the response
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
 }
@@ -8,9 +8,15 @@ import (
 )
 
 type Config struct {
-	DbURL  string `json:"db_url"`
-	User   string `json:"current_user_name"`
-	Client Client `json:"-"`
+	DbURL       string        `json:"db_url"`
+	User        string        `json:"current_user_name"`
+	SecretKey   string        `json:"secret_key,omitempty"`
+	AutoMigrate bool          `json:"auto_migrate,omitempty"`
+	HTTP        HTTPConfig    `json:"http"`
+	Agg         AggConfig     `json:"agg"`
+	WebSub      WebSubConfig  `json:"websub"`
+	Archive     ArchiveConfig `json:"archive"`
+	Client      Client        `json:"-"`
 }
 
 const configFileName = ".gatorconfig.json"
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"time"
+)
+
+// Modes of importing the items found on a feed's first fetch
+const (
+	FirstFetchAll      = "all"
+	FirstFetchLatest   = "latest"
+	FirstFetchDays     = "days"
+	FirstFetchMarkRead = "mark_read"
+)
+
+// FirstFetchPolicy decides which items of a feed's first fetch are imported
+type FirstFetchPolicy struct {
+	Mode string
+	// Limit is the number of items for latest and the number of days for days
+	Limit int
+}
+
+// ParseFirstFetchPolicy reads a policy written as all, mark_read, latest:<items> or days:<days>
+func ParseFirstFetchPolicy(value string) (FirstFetchPolicy, error) {
+	mode, limit, err := parsePolicy("first fetch", value, FirstFetchAll, map[string]bool{
+		FirstFetchAll:      false,
+		FirstFetchMarkRead: false,
+		FirstFetchLatest:   true,
+		FirstFetchDays:     true,
+	}, "all, mark_read, latest:<items> or days:<days>")
+	if err != nil {
+		return FirstFetchPolicy{}, err
+	}
+	return FirstFetchPolicy{Mode: mode, Limit: limit}, nil
+}
+
+// MaxAge is how old items imported under the days mode may be
+func (p FirstFetchPolicy) MaxAge() time.Duration {
+	return days(p.Limit)
+}
+
+func (p FirstFetchPolicy) String() string {
+	if p.Mode == "" {
+		return FirstFetchAll
+	}
+	return policyString(p.Mode, p.Limit)
+}
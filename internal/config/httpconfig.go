@@ -0,0 +1,199 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+const (
+	DefaultTimeout = 60 * time.Second
+	DefaultAccept  = "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.8"
+)
+
+// HTTPConfig holds the settings used when building the HTTP client
+type HTTPConfig struct {
+	Timeout            string   `json:"timeout,omitempty"`
+	UserAgent          string   `json:"user_agent,omitempty"`
+	Accept             string   `json:"accept,omitempty"`
+	ProxyURL           string   `json:"proxy_url,omitempty"`
+	CAFiles            []string `json:"ca_files,omitempty"`
+	MinTLSVersion      string   `json:"min_tls_version,omitempty"`
+	HostMinInterval    string   `json:"host_min_interval,omitempty"`
+	HostMaxConcurrency int      `json:"host_max_concurrency,omitempty"`
+	RetryAttempts      int      `json:"retry_attempts,omitempty"`
+	RetryBaseDelay     string   `json:"retry_base_delay,omitempty"`
+	RetryMaxDelay      string   `json:"retry_max_delay,omitempty"`
+}
+
+// ClientOptions resolves the configured values (and their defaults) into options for NewClient
+func (h HTTPConfig) ClientOptions() (ClientOptions, error) {
+	timeout, err := parseDurationSetting("timeout", h.Timeout, DefaultTimeout)
+	if err != nil {
+		return ClientOptions{}, err
+	}
+
+	hostInterval, err := h.MinInterval()
+	if err != nil {
+		return ClientOptions{}, err
+	}
+
+	proxy, err := h.proxyFunc()
+	if err != nil {
+		return ClientOptions{}, err
+	}
+
+	rootCAs, err := h.rootCAs()
+	if err != nil {
+		return ClientOptions{}, err
+	}
+
+	minTLS, err := h.minTLSVersion()
+	if err != nil {
+		return ClientOptions{}, err
+	}
+
+	retryBase, err := parseDurationSetting("retry_base_delay", h.RetryBaseDelay, DefaultRetryBaseDelay)
+	if err != nil {
+		return ClientOptions{}, err
+	}
+
+	retryMax, err := parseDurationSetting("retry_max_delay", h.RetryMaxDelay, DefaultRetryMaxDelay)
+	if err != nil {
+		return ClientOptions{}, err
+	}
+
+	retryAttempts := h.RetryAttempts
+	if retryAttempts <= 0 {
+		retryAttempts = DefaultRetryAttempts
+	}
+
+	accept := h.Accept
+	if accept == "" {
+		accept = DefaultAccept
+	}
+
+	return ClientOptions{
+		Timeout:            timeout,
+		UserAgent:          h.userAgent(),
+		Accept:             accept,
+		Headers:            make(map[string]string),
+		Proxy:              proxy,
+		RootCAs:            rootCAs,
+		MinTLSVersion:      minTLS,
+		HostMinInterval:    hostInterval,
+		HostMaxConcurrency: h.MaxConcurrency(),
+		RetryAttempts:      retryAttempts,
+		RetryBaseDelay:     retryBase,
+		RetryMaxDelay:      retryMax,
+	}, nil
+}
+
+// MinInterval returns the minimum time between two requests to the same host
+func (h HTTPConfig) MinInterval() (time.Duration, error) {
+	return parseDurationSetting("host_min_interval", h.HostMinInterval, DefaultHostMinInterval)
+}
+
+// MaxConcurrency returns how many requests may be in flight to the same host
+func (h HTTPConfig) MaxConcurrency() int {
+	if h.HostMaxConcurrency <= 0 {
+		return DefaultHostMaxConcurrency
+	}
+	return h.HostMaxConcurrency
+}
+
+func (h HTTPConfig) userAgent() string {
+	if h.UserAgent != "" {
+		return h.UserAgent
+	}
+
+	// On Unix-like systems (Linux, macOS)
+	username := os.Getenv("USER")
+
+	// On Windows
+	if username == "" {
+		username = os.Getenv("USERNAME")
+	}
+
+	return fmt.Sprintf("%s_gator", username)
+}
+
+// proxyFunc uses proxy_url when set, otherwise HTTP_PROXY/HTTPS_PROXY. NO_PROXY is respected either way
+func (h HTTPConfig) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if h.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyUrl, err := url.Parse(h.ProxyURL)
+	if err != nil || proxyUrl.Scheme == "" || proxyUrl.Host == "" {
+		return nil, fmt.Errorf("invalid proxy_url %q", h.ProxyURL)
+	}
+
+	proxyConfig := httpproxy.FromEnvironment()
+	proxyConfig.HTTPProxy = h.ProxyURL
+	proxyConfig.HTTPSProxy = h.ProxyURL
+	proxy := proxyConfig.ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
+
+// rootCAs adds the configured CA bundles to the system pool, nil means use the system pool as is
+func (h HTTPConfig) rootCAs() (*x509.CertPool, error) {
+	if len(h.CAFiles) == 0 {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	for _, caFile := range h.CAFiles {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file %s: %v", caFile, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA file %s", caFile)
+		}
+	}
+
+	return pool, nil
+}
+
+func (h HTTPConfig) minTLSVersion() (uint16, error) {
+	switch h.MinTLSVersion {
+	case "":
+		return tls.VersionTLS12, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid min_tls_version %q, expected one of 1.0, 1.1, 1.2 or 1.3", h.MinTLSVersion)
+	}
+}
+
+func parseDurationSetting(name string, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %v", name, value, err)
+	}
+	return duration, nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProxyURLRespectsNoProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	options, err := HTTPConfig{ProxyURL: "http://proxy.example.com:3128"}.ClientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url   string
+		proxy string
+	}{
+		{url: "https://feeds.example.org/rss", proxy: "http://proxy.example.com:3128"},
+		{url: "http://feeds.example.org/rss", proxy: "http://proxy.example.com:3128"},
+		{url: "https://internal.example.com/rss", proxy: ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		proxy, err := options.Proxy(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.url, err)
+		}
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if got != tt.proxy {
+			t.Errorf("%s: expected proxy %q, got %q", tt.url, tt.proxy, got)
+		}
+	}
+}
+
+func TestMinTLSVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint16
+		invalid bool
+	}{
+		{value: "", want: tls.VersionTLS12},
+		{value: "1.0", want: tls.VersionTLS10},
+		{value: "1.1", want: tls.VersionTLS11},
+		{value: "1.2", want: tls.VersionTLS12},
+		{value: "1.3", want: tls.VersionTLS13},
+		{value: "1.4", invalid: true},
+		{value: "TLS1.3", invalid: true},
+	}
+	for _, tt := range tests {
+		got, err := HTTPConfig{MinTLSVersion: tt.value}.minTLSVersion()
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%q should be invalid, got %v", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.value, tt.want, got, err)
+		}
+	}
+}
+
+func TestClientOptionsErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config HTTPConfig
+	}{
+		{name: "missing CA file", config: HTTPConfig{CAFiles: []string{filepath.Join(dir, "missing.pem")}}},
+		{name: "CA file without certificates", config: HTTPConfig{CAFiles: []string{notPEM}}},
+		{name: "unknown TLS version", config: HTTPConfig{MinTLSVersion: "1.4"}},
+		{name: "proxy without a scheme", config: HTTPConfig{ProxyURL: "proxy.example.com:3128"}},
+		{name: "unparseable timeout", config: HTTPConfig{Timeout: "soon"}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.config.ClientOptions(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+// writeCA saves the certificate of a TLS test server as a PEM file and returns its path
+func writeCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, block, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fetchWith fetches url with a client built from config
+func fetchWith(t *testing.T, config HTTPConfig, url string) error {
+	t.Helper()
+
+	options, err := config.ClientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.RetryAttempts = 1
+	client := NewClient(options)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func TestCAFilesTrustServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+
+	if err := fetchWith(t, HTTPConfig{}, server.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("a server signed by an unknown CA should be refused, got %v", err)
+	}
+	if err := fetchWith(t, HTTPConfig{CAFiles: []string{writeCA(t, server)}}, server.URL); err != nil {
+		t.Errorf("the server should be trusted with its CA file: %v", err)
+	}
+}
+
+func TestMinTLSVersionRefusesOlderServers(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	ca := writeCA(t, server)
+
+	if err := fetchWith(t, HTTPConfig{CAFiles: []string{ca}, MinTLSVersion: "1.2"}, server.URL); err != nil {
+		t.Errorf("TLS 1.2 should be accepted with min_tls_version 1.2: %v", err)
+	}
+	if err := fetchWith(t, HTTPConfig{CAFiles: []string{ca}, MinTLSVersion: "1.3"}, server.URL); err == nil {
+		t.Error("a TLS 1.2 server should be refused with min_tls_version 1.3")
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	DefaultHostMinInterval    = time.Second
+	DefaultHostMaxConcurrency = 2
+)
+
+// hostLimiter spaces out requests to the same site and caps how many can be in flight at once
+type hostLimiter struct {
+	mu             sync.Mutex
+	minInterval    time.Duration
+	maxConcurrency int
+	hosts          map[string]*hostState
+}
+
+type hostState struct {
+	slots       chan struct{}
+	nextAllowed time.Time
+}
+
+func newHostLimiter(minInterval time.Duration, maxConcurrency int) *hostLimiter {
+	if minInterval < 0 {
+		minInterval = 0
+	}
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+
+	return &hostLimiter{
+		minInterval:    minInterval,
+		maxConcurrency: maxConcurrency,
+		hosts:          make(map[string]*hostState),
+	}
+}
+
+func (l *hostLimiter) host(name string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, exists := l.hosts[name]
+	if !exists {
+		state = &hostState{
+			slots: make(chan struct{}, l.maxConcurrency),
+		}
+		l.hosts[name] = state
+	}
+	return state
+}
+
+// limiterKey returns the registrable domain of host (e.g. substack.com for blog.substack.com), so
+// subdomains of one site share their limits. IP addresses and names without a known public suffix
+// (e.g. localhost) are limited on their own
+func limiterKey(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	site, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return site
+}
+
+// acquire blocks until a request to host may be made, the returned function frees the slot again
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	state := l.host(limiterKey(host))
+
+	// Take a concurrency slot for the host
+	select {
+	case state.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() { <-state.slots })
+	}
+
+	// Reserve the next start time so requests to the host are at least minInterval apart
+	l.mu.Lock()
+	now := time.Now()
+	start := state.nextAllowed
+	if start.Before(now) {
+		start = now
+	}
+	state.nextAllowed = start.Add(l.minInterval)
+	l.mu.Unlock()
+
+	wait := time.Until(start)
+	if wait <= 0 {
+		return release, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+}
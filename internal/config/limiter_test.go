@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterKey(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "example.com", want: "example.com"},
+		{host: "blog.substack.com", want: "substack.com"},
+		{host: "Feeds.Example.COM.", want: "example.com"},
+		{host: "news.bbc.co.uk", want: "bbc.co.uk"},
+		// github.io is a public suffix of its own, every user's site is separate
+		{host: "alice.github.io", want: "alice.github.io"},
+		{host: "localhost", want: "localhost"},
+		{host: "127.0.0.1", want: "127.0.0.1"},
+		{host: "::1", want: "::1"},
+	}
+	for _, tt := range tests {
+		if got := limiterKey(tt.host); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.host, tt.want, got)
+		}
+	}
+}
+
+// acquireWithin acquires host and fails the test unless it took about as long as expected
+func acquireWithin(t *testing.T, l *hostLimiter, host string, min time.Duration, max time.Duration) func() {
+	t.Helper()
+
+	start := time.Now()
+	release, err := l.acquire(context.Background(), host)
+	if err != nil {
+		t.Fatalf("acquiring %s failed: %v", host, err)
+	}
+	if waited := time.Since(start); waited < min || waited > max {
+		t.Errorf("acquiring %s took %v, expected between %v and %v", host, waited, min, max)
+	}
+	return release
+}
+
+func TestHostLimiterSharesSubdomainBucket(t *testing.T) {
+	interval := 100 * time.Millisecond
+	l := newHostLimiter(interval, 2)
+
+	acquireWithin(t, l, "a.example.com", 0, interval/2)()
+	// Another subdomain waits for the interval, an unrelated site doesn't
+	acquireWithin(t, l, "other.org", 0, interval/2)()
+	acquireWithin(t, l, "b.example.com", interval*3/4, 3*interval)()
+}
+
+func TestHostLimiterCapsConcurrency(t *testing.T) {
+	l := newHostLimiter(0, 1)
+
+	release := acquireWithin(t, l, "a.example.com", 0, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "b.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("a second request to the site should wait for the first, got %v", err)
+	}
+
+	release()
+	// Releasing twice doesn't free a slot someone else holds
+	release()
+	acquireWithin(t, l, "b.example.com", 0, time.Second)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "a.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the slot should still be taken, got %v", err)
+	}
+}
+
+func TestHostLimiterWaitIsCancelled(t *testing.T) {
+	l := newHostLimiter(time.Hour, 2)
+
+	acquireWithin(t, l, "example.com", 0, time.Second)()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "www.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiting for the interval should stop with the context, got %v", err)
+	}
+
+	// The cancelled request gave its slot back
+	state := l.host("example.com")
+	if len(state.slots) != 0 {
+		t.Errorf("expected no slots in use, got %d", len(state.slots))
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parsePolicy reads a policy written as <mode> or <mode>:<n>, as first fetch and retention policies are.
+// modes has every valid mode, true for the ones taking a positive number. An empty value is defaultMode
+func parsePolicy(kind string, value string, defaultMode string, modes map[string]bool, usage string) (string, int, error) {
+	mode, number, hasNumber := strings.Cut(strings.TrimSpace(value), ":")
+	if mode == "" {
+		mode = defaultMode
+	}
+
+	counted, ok := modes[mode]
+	switch {
+	case !ok:
+		return "", 0, fmt.Errorf("invalid %s policy %q, expected %s", kind, value, usage)
+	case !counted && hasNumber:
+		return "", 0, fmt.Errorf("invalid %s policy %q, %s doesn't take a number", kind, value, mode)
+	case !counted:
+		return mode, 0, nil
+	}
+
+	limit, err := strconv.Atoi(number)
+	if !hasNumber || err != nil || limit <= 0 {
+		return "", 0, fmt.Errorf("invalid %s policy %q, expected %s:<positive number>", kind, value, mode)
+	}
+	return mode, limit, nil
+}
+
+// policyString writes a policy the way parsePolicy reads it
+func policyString(mode string, limit int) string {
+	if limit > 0 {
+		return fmt.Sprintf("%s:%d", mode, limit)
+	}
+	return mode
+}
+
+// days is the duration of a days:<n> policy
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
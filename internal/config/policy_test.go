@@ -0,0 +1,90 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFirstFetchPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    FirstFetchPolicy
+		invalid bool
+	}{
+		{value: "", want: FirstFetchPolicy{Mode: FirstFetchAll}},
+		{value: "all", want: FirstFetchPolicy{Mode: FirstFetchAll}},
+		{value: " mark_read ", want: FirstFetchPolicy{Mode: FirstFetchMarkRead}},
+		{value: "latest:20", want: FirstFetchPolicy{Mode: FirstFetchLatest, Limit: 20}},
+		{value: "days:7", want: FirstFetchPolicy{Mode: FirstFetchDays, Limit: 7}},
+		{value: "latest", invalid: true},
+		{value: "latest:0", invalid: true},
+		{value: "days:-1", invalid: true},
+		{value: "days:week", invalid: true},
+		{value: "mark_read:3", invalid: true},
+		{value: ":3", invalid: true},
+		{value: "forever", invalid: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFirstFetchPolicy(tt.value)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%q should be invalid, got %+v", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %+v, got %+v (%v)", tt.value, tt.want, got, err)
+		}
+	}
+}
+
+func TestParseRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RetentionPolicy
+		invalid bool
+	}{
+		{value: "", want: RetentionPolicy{Mode: RetentionForever}},
+		{value: "forever", want: RetentionPolicy{Mode: RetentionForever}},
+		{value: "latest:100", want: RetentionPolicy{Mode: RetentionLatest, Limit: 100}},
+		{value: "days:30", want: RetentionPolicy{Mode: RetentionDays, Limit: 30}},
+		{value: "forever:3", invalid: true},
+		{value: "days", invalid: true},
+		{value: "latest:0", invalid: true},
+		{value: "mark_read", invalid: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetentionPolicy(tt.value)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%q should be invalid, got %+v", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %+v, got %+v (%v)", tt.value, tt.want, got, err)
+		}
+	}
+}
+
+func TestPolicyString(t *testing.T) {
+	for _, value := range []string{"all", "mark_read", "latest:20", "days:7"} {
+		policy, err := ParseFirstFetchPolicy(value)
+		if err != nil || policy.String() != value {
+			t.Errorf("%q was written back as %q (%v)", value, policy.String(), err)
+		}
+	}
+	for _, value := range []string{"forever", "latest:100", "days:30"} {
+		policy, err := ParseRetentionPolicy(value)
+		if err != nil || policy.String() != value {
+			t.Errorf("%q was written back as %q (%v)", value, policy.String(), err)
+		}
+	}
+
+	if (FirstFetchPolicy{}).String() != FirstFetchAll || (RetentionPolicy{}).String() != RetentionForever {
+		t.Error("the zero policies should be written as their default mode")
+	}
+	if (RetentionPolicy{Mode: RetentionDays, Limit: 2}).MaxAge() != 48*time.Hour {
+		t.Error("days:2 should keep posts for 48 hours")
+	}
+}
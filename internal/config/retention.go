@@ -0,0 +1,44 @@
+package config
+
+import (
+	"time"
+)
+
+// Modes of keeping the posts of a feed
+const (
+	RetentionForever = "forever"
+	RetentionLatest  = "latest"
+	RetentionDays    = "days"
+)
+
+// RetentionPolicy decides which posts of a feed survive pruning, starred posts are always kept
+type RetentionPolicy struct {
+	Mode string
+	// Limit is the number of posts for latest and the number of days for days
+	Limit int
+}
+
+// ParseRetentionPolicy reads a policy written as forever, latest:<posts> or days:<days>
+func ParseRetentionPolicy(value string) (RetentionPolicy, error) {
+	mode, limit, err := parsePolicy("retention", value, RetentionForever, map[string]bool{
+		RetentionForever: false,
+		RetentionLatest:  true,
+		RetentionDays:    true,
+	}, "forever, latest:<posts> or days:<days>")
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	return RetentionPolicy{Mode: mode, Limit: limit}, nil
+}
+
+// MaxAge is how old posts kept under the days mode may be
+func (p RetentionPolicy) MaxAge() time.Duration {
+	return days(p.Limit)
+}
+
+func (p RetentionPolicy) String() string {
+	if p.Mode == "" {
+		return RetentionForever
+	}
+	return policyString(p.Mode, p.Limit)
+}
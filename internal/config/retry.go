@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultRetryAttempts  = 3
+	DefaultRetryBaseDelay = time.Second
+	DefaultRetryMaxDelay  = 30 * time.Second
+)
+
+// retryPolicy is exponential backoff with jitter, attempts counts the first try as well
+type retryPolicy struct {
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// delay returns how long to wait after the given (1 based) attempt failed
+func (p retryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	backoff := p.baseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.maxDelay {
+		backoff = p.maxDelay
+	}
+
+	// Wait at least half the backoff, the other half is random so clients don't retry in lockstep
+	wait := backoff/2 + rand.N(backoff/2+1)
+
+	// Honour a Retry-After in seconds from an overloaded server, within the max delay
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter := time.Duration(seconds) * time.Second
+			wait = min(max(wait, retryAfter), p.maxDelay)
+		}
+	}
+
+	return wait
+}
+
+// shouldRetry reports whether a failed attempt was caused by something transient
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	// The caller gave up, retrying won't help
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err == nil {
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// Connection refused/reset, DNS failures and connections closed halfway through
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+func sleepContext(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type requestLabelKey struct{}
+
+// WithRequestLabel attaches a description (e.g. the feed name) to requests made with ctx, it is included in retry logs
+func WithRequestLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, requestLabelKey{}, label)
+}
+
+func describeRequest(ctx context.Context) string {
+	label, ok := ctx.Value(requestLabelKey{}).(string)
+	if !ok || label == "" {
+		return ""
+	}
+	return " (" + label + ")"
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	policy := retryPolicy{attempts: 5, baseDelay: time.Second, maxDelay: 8 * time.Second}
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		min        time.Duration
+		max        time.Duration
+	}{
+		{name: "first retry", attempt: 1, min: 500 * time.Millisecond, max: time.Second},
+		{name: "doubles", attempt: 2, min: time.Second, max: 2 * time.Second},
+		{name: "doubles again", attempt: 3, min: 2 * time.Second, max: 4 * time.Second},
+		{name: "capped", attempt: 5, min: 4 * time.Second, max: 8 * time.Second},
+		{name: "capped when the shift overflows", attempt: 70, min: 4 * time.Second, max: 8 * time.Second},
+		{name: "retry after", attempt: 1, retryAfter: "3", min: 3 * time.Second, max: 3 * time.Second},
+		{name: "retry after shorter than the backoff", attempt: 3, retryAfter: "1", min: 2 * time.Second, max: 4 * time.Second},
+		{name: "retry after capped", attempt: 1, retryAfter: "60", min: 8 * time.Second, max: 8 * time.Second},
+		{name: "retry after as a date is ignored", attempt: 1, retryAfter: "Wed, 21 Oct 2026 07:28:00 GMT", min: 500 * time.Millisecond, max: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			seen := map[time.Duration]bool{}
+			for i := 0; i < 200; i++ {
+				wait := policy.delay(tt.attempt, resp)
+				if wait < tt.min || wait > tt.max {
+					t.Fatalf("expected a delay between %v and %v, got %v", tt.min, tt.max, wait)
+				}
+				seen[wait] = true
+			}
+			// The jitter spreads the delays out unless a Retry-After fixed them
+			if tt.min != tt.max && len(seen) < 2 {
+				t.Errorf("expected jittered delays, always got %v", seen)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error for a timed out request
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return false }
+
+func TestShouldRetry(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		status int
+		err    error
+		want   bool
+	}{
+		{name: "bad gateway", status: http.StatusBadGateway, want: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, want: true},
+		{name: "gateway timeout", status: http.StatusGatewayTimeout, want: true},
+		{name: "ok", status: http.StatusOK, want: false},
+		{name: "not found", status: http.StatusNotFound, want: false},
+		{name: "internal error", status: http.StatusInternalServerError, want: false},
+		{name: "timeout", err: &url.Error{Op: "Get", URL: "https://example.com", Err: timeoutError{}}, want: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: true},
+		{name: "dns failure", err: &net.DNSError{Err: "no such host", Name: "example.invalid"}, want: true},
+		{name: "closed halfway", err: io.ErrUnexpectedEOF, want: true},
+		{name: "other error", err: errors.New("unsupported protocol scheme"), want: false},
+		{name: "cancelled", ctx: cancelled, status: http.StatusServiceUnavailable, want: false},
+		{name: "cancelled with an error", ctx: cancelled, err: io.ErrUnexpectedEOF, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			req := httptest.NewRequest(http.MethodGet, "https://example.com", nil).WithContext(ctx)
+
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+			if got := shouldRetry(req, resp, tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClientRetriesUnavailable(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every other request is turned away
+		if requests.Add(1)%2 == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(server.Close)
+
+	var retries []string
+	client := NewClient(ClientOptions{
+		Timeout:        5 * time.Second,
+		RetryAttempts:  3,
+		RetryBaseDelay: 10 * time.Millisecond,
+		RetryMaxDelay:  50 * time.Millisecond,
+		Logf: func(format string, v ...interface{}) {
+			retries = append(retries, format)
+		},
+	})
+
+	tests := []struct {
+		method   string
+		status   int
+		requests int32
+	}{
+		{method: http.MethodGet, status: http.StatusOK, requests: 2},
+		{method: http.MethodHead, status: http.StatusOK, requests: 2},
+		// A request with a body might not be safe to send twice
+		{method: http.MethodPost, status: http.StatusServiceUnavailable, requests: 1},
+	}
+	for _, tt := range tests {
+		requests.Store(0)
+		retries = nil
+
+		req, err := http.NewRequest(tt.method, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s failed: %v", tt.method, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status || requests.Load() != tt.requests {
+			t.Errorf("%s: expected %d after %d requests, got %d after %d", tt.method, tt.status, tt.requests, resp.StatusCode, requests.Load())
+		}
+		if len(retries) != int(tt.requests)-1 {
+			t.Errorf("%s: expected every retry to be logged, got %d lines", tt.method, len(retries))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// secretKey decodes the base64 encoded AES-256 key stored in the config file
+func (c *Config) secretKey() ([]byte, error) {
+	if c.SecretKey == "" {
+		return nil, errors.New("no secret_key set in config, generate one with `openssl rand -base64 32`")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(c.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("secret_key is not valid base64: %v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret_key must decode to 32 bytes, got %d", len(key))
+	}
+
+	return key, nil
+}
+
+func (c *Config) newAEAD() (cipher.AEAD, error) {
+	key, err := c.secretKey()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret seals the plaintext with AES-GCM, the nonce is prepended to the result
+func (c *Config) EncryptSecret(plaintext string) ([]byte, error) {
+	aead, err := c.newAEAD()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+// DecryptSecret reverses EncryptSecret
+func (c *Config) DecryptSecret(ciphertext []byte) (string, error) {
+	aead, err := c.newAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt secret, was secret_key changed? %v", err)
+	}
+
+	return string(plaintext), nil
+}
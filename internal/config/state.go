@@ -1,12 +1,19 @@
 package config
 
 import (
+	"context"
+	"database/sql"
+
 	"github.com/git-cst/bootdev_gator/internal/database"
 )
 
 type State struct {
-	Config      *Config
-	Db          *database.Queries
+	Config *Config
+	Db     database.Store
+	// DBConn is the connection Db runs on, used where raw access is needed (e.g. migrations)
+	DBConn      *sql.DB
 	Logger      *LogInstance
 	CurrentUser *database.User
+	// Ctx is cancelled when the process is asked to shut down (Ctrl-C, SIGTERM)
+	Ctx context.Context
 }
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultWebSubListenAddr   = ":8085"
+	DefaultWebSubLease        = 7 * 24 * time.Hour
+	DefaultWebSubPollInterval = 6 * time.Hour
+)
+
+// WebSubConfig holds the settings for receiving pushed feed updates, it is disabled without a callback_url
+type WebSubConfig struct {
+	CallbackURL  string `json:"callback_url,omitempty"`
+	ListenAddr   string `json:"listen_addr,omitempty"`
+	Lease        string `json:"lease,omitempty"`
+	PollInterval string `json:"poll_interval,omitempty"`
+}
+
+// Enabled reports whether agg should subscribe to hubs and run the callback endpoint
+func (w WebSubConfig) Enabled() bool {
+	return w.CallbackURL != ""
+}
+
+// Callback returns the public URL hubs deliver to, without trailing slash
+func (w WebSubConfig) Callback() (string, error) {
+	callback, err := url.Parse(w.CallbackURL)
+	if err != nil || callback.Scheme == "" || callback.Host == "" {
+		return "", fmt.Errorf("invalid websub callback_url %q", w.CallbackURL)
+	}
+	return strings.TrimSuffix(w.CallbackURL, "/"), nil
+}
+
+// Addr returns the address the callback endpoint listens on
+func (w WebSubConfig) Addr() string {
+	if w.ListenAddr == "" {
+		return DefaultWebSubListenAddr
+	}
+	return w.ListenAddr
+}
+
+// LeaseDuration returns the subscription lease requested from hubs
+func (w WebSubConfig) LeaseDuration() (time.Duration, error) {
+	return parseDurationSetting("websub lease", w.Lease, DefaultWebSubLease)
+}
+
+// FallbackPollInterval returns how often feeds that are pushed to us are still polled
+func (w WebSubConfig) FallbackPollInterval() (time.Duration, error) {
+	return parseDurationSetting("websub poll_interval", w.PollInterval, DefaultWebSubPollInterval)
+}
@@ -0,0 +1,57 @@
+// Package databasetest provides an in-memory SQLite database.Store, so handlers can be exercised
+// against the real queries without a running Postgres
+package databasetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/git-cst/bootdev_gator/internal/database"
+	"github.com/git-cst/bootdev_gator/sql/migrations"
+)
+
+// DB is a migrated in-memory SQLite store. It also records every Notify call, as SQLite has nothing
+// to deliver them to
+type DB struct {
+	database.Store
+
+	mu            sync.Mutex
+	notifications []database.NotifyParams
+}
+
+// New returns an empty database, which is closed when the test ends
+func New(t testing.TB) *DB {
+	t.Helper()
+
+	conn, store, err := database.Open("sqlite://:memory:")
+	if err != nil {
+		t.Fatalf("could not open the test database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	// Every connection to :memory: is a database of its own
+	conn.SetMaxOpenConns(1)
+
+	provider, err := migrations.NewProvider(database.DriverSQLite, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("could not migrate the test database: %v", err)
+	}
+	return &DB{Store: store}
+}
+
+func (db *DB) Notify(ctx context.Context, arg database.NotifyParams) error {
+	db.mu.Lock()
+	db.notifications = append(db.notifications, arg)
+	db.mu.Unlock()
+	return db.Store.Notify(ctx, arg)
+}
+
+// Notifications returns every notification sent so far, in order
+func (db *DB) Notifications() []database.NotifyParams {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]database.NotifyParams(nil), db.notifications...)
+}
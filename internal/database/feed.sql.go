@@ -13,24 +13,177 @@ import (
 	"github.com/google/uuid"
 )
 
+const claimDueFeed = `-- name: ClaimDueFeed :one
+UPDATE feed
+SET
+    updated_at = $1,
+    fetch_lease_until = (now() AT TIME ZONE 'UTC') + $2::float8 * interval '1 second'
+WHERE id = (
+    SELECT due.id
+    FROM feed AS due
+    WHERE (due.fetch_lease_until IS NULL OR due.fetch_lease_until < now() AT TIME ZONE 'UTC')
+    AND (due.last_fetched_at IS NULL OR due.last_fetched_at < $3)
+    ORDER BY due.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_lease_until, last_body_hash, first_fetch, retention, first_fetched_at
+`
+
+type ClaimDueFeedParams struct {
+	Now          time.Time
+	LeaseSeconds float64
+	DueBefore    sql.NullTime
+}
+
+func (q *Queries) ClaimDueFeed(ctx context.Context, arg ClaimDueFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimDueFeed, arg.Now, arg.LeaseSeconds, arg.DueBefore)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchLeaseUntil,
+		&i.LastBodyHash,
+		&i.FirstFetch,
+		&i.Retention,
+		&i.FirstFetchedAt,
+	)
+	return i, err
+}
+
+const claimFeed = `-- name: ClaimFeed :one
+UPDATE feed
+SET
+    updated_at = $1,
+    fetch_lease_until = (now() AT TIME ZONE 'UTC') + $2::float8 * interval '1 second'
+WHERE id = $3
+AND (fetch_lease_until IS NULL OR fetch_lease_until < now() AT TIME ZONE 'UTC')
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_lease_until, last_body_hash, first_fetch, retention, first_fetched_at
+`
+
+type ClaimFeedParams struct {
+	Now          time.Time
+	LeaseSeconds float64
+	ID           uuid.UUID
+}
+
+func (q *Queries) ClaimFeed(ctx context.Context, arg ClaimFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimFeed, arg.Now, arg.LeaseSeconds, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchLeaseUntil,
+		&i.LastBodyHash,
+		&i.FirstFetch,
+		&i.Retention,
+		&i.FirstFetchedAt,
+	)
+	return i, err
+}
+
+const claimNextFeedToFetch = `-- name: ClaimNextFeedToFetch :one
+UPDATE feed
+SET
+    updated_at = $1,
+    fetch_lease_until = (now() AT TIME ZONE 'UTC') + $2::float8 * interval '1 second'
+WHERE id = (
+    SELECT next.id
+    FROM feed AS next
+    WHERE (next.fetch_lease_until IS NULL OR next.fetch_lease_until < now() AT TIME ZONE 'UTC')
+    -- Feeds pushed to us over WebSub are only polled as a fallback
+    AND NOT EXISTS (
+        SELECT 1
+        FROM websub_subscriptions AS ws
+        WHERE ws.feed_id = next.id
+        AND ws.state = 'active'
+        AND ws.lease_expires_at > $1
+        AND next.last_fetched_at >= $3
+    )
+    ORDER BY next.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_lease_until, last_body_hash, first_fetch, retention, first_fetched_at
+`
+
+type ClaimNextFeedToFetchParams struct {
+	Now          time.Time
+	LeaseSeconds float64
+	PushedBefore sql.NullTime
+}
+
+// Leases are set and compared by the database clock in UTC, so instances with skewed clocks or other
+// time zones agree on when one runs out
+func (q *Queries) ClaimNextFeedToFetch(ctx context.Context, arg ClaimNextFeedToFetchParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimNextFeedToFetch, arg.Now, arg.LeaseSeconds, arg.PushedBefore)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchLeaseUntil,
+		&i.LastBodyHash,
+		&i.FirstFetch,
+		&i.Retention,
+		&i.FirstFetchedAt,
+	)
+	return i, err
+}
+
+const countOtherFollowers = `-- name: CountOtherFollowers :one
+SELECT
+    COUNT(*)
+FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2
+`
+
+type CountOtherFollowersParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CountOtherFollowers(ctx context.Context, arg CountOtherFollowersParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOtherFollowers, arg.FeedID, arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feed(created_at, updated_at, name, url, user_id)
+INSERT INTO feed(created_at, updated_at, name, url, user_id, first_fetch)
 VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_lease_until, last_body_hash, first_fetch, retention, first_fetched_at
 `
 
 type CreateFeedParams struct {
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Name       string
+	Url        string
+	UserID     uuid.UUID
+	FirstFetch sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -40,6 +193,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.FirstFetch,
 	)
 	var i Feed
 	err := row.Scan(
@@ -50,6 +204,11 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchLeaseUntil,
+		&i.LastBodyHash,
+		&i.FirstFetch,
+		&i.Retention,
+		&i.FirstFetchedAt,
 	)
 	return i, err
 }
@@ -110,12 +269,94 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feed
+WHERE id = $1
+`
+
+// Its posts, follows and everything else referring to it are deleted with it
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT
+    id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_lease_until, last_body_hash, first_fetch, retention, first_fetched_at
+FROM feed
+ORDER BY name
+`
+
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchLeaseUntil,
+			&i.LastBodyHash,
+			&i.FirstFetch,
+			&i.Retention,
+			&i.FirstFetchedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT
+    id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_lease_until, last_body_hash, first_fetch, retention, first_fetched_at
+FROM feed
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchLeaseUntil,
+		&i.LastBodyHash,
+		&i.FirstFetch,
+		&i.Retention,
+		&i.FirstFetchedAt,
+	)
+	return i, err
+}
+
 const getFeedByUrl = `-- name: GetFeedByUrl :one
 SELECT 
     f.id as ID,
     f.name as Name,
     f.url as Url,
     u.name as User,
+    f.user_id,
     f.created_at as Created_at,
     f.updated_at as updated_at
 FROM feed as f
@@ -130,6 +371,7 @@ type GetFeedByUrlRow struct {
 	Name      string
 	Url       string
 	User      string
+	UserID    uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -142,6 +384,7 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (GetFeedByUrlRow
 		&i.Name,
 		&i.Url,
 		&i.User,
+		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -152,13 +395,24 @@ const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
     feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
     f.name as feed_name,
-    u.name as username
+    u.name as username,
+    (
+        SELECT COUNT(*)
+        FROM posts AS p
+        WHERE p.feed_id = feed_follows.feed_id
+        AND NOT EXISTS (
+            SELECT 1
+            FROM post_reads AS pr
+            WHERE pr.post_id = p.id AND pr.user_id = feed_follows.user_id
+        )
+    ) AS unread
 FROM feed_follows
 INNER JOIN feed as f
 ON f.id = feed_follows.feed_id
 INNER JOIN users as u
 ON u.id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
+ORDER BY f.name
 `
 
 type GetFeedFollowsForUserRow struct {
@@ -169,6 +423,7 @@ type GetFeedFollowsForUserRow struct {
 	FeedID    uuid.UUID
 	FeedName  string
 	Username  string
+	Unread    int64
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -188,6 +443,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.FeedID,
 			&i.FeedName,
 			&i.Username,
+			&i.Unread,
 		); err != nil {
 			return nil, err
 		}
@@ -251,57 +507,67 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
+const getFeedsByUrlOrName = `-- name: GetFeedsByUrlOrName :many
 SELECT
-    id, created_at, updated_at, name, url, user_id, last_fetched_at
+    id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_lease_until, last_body_hash, first_fetch, retention, first_fetched_at
 FROM feed
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+WHERE url = $1 OR name = $1
+ORDER BY name
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) GetFeedsByUrlOrName(ctx context.Context, url string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByUrlOrName, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchLeaseUntil,
+			&i.LastBodyHash,
+			&i.FirstFetch,
+			&i.Retention,
+			&i.FirstFetchedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :one
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
 UPDATE feed
-SET 
-    updated_at = $1,
-    last_fetched_at = $2
-WHERE id = $3
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+SET
+    last_fetched_at = $2,
+    fetch_lease_until = NULL
+WHERE id = $1
 `
 
-type MarkFeedFetchedParams struct {
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
+type ReleaseFeedLeaseParams struct {
 	ID            uuid.UUID
+	LastFetchedAt sql.NullTime
 }
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.UpdatedAt, arg.LastFetchedAt, arg.ID)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+// last_fetched_at is set after every attempt, failed ones included, so feeds are claimed in turn
+func (q *Queries) ReleaseFeedLease(ctx context.Context, arg ReleaseFeedLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, arg.ID, arg.LastFetchedAt)
+	return err
 }
 
 const removeFollowForUser = `-- name: RemoveFollowForUser :exec
@@ -321,3 +587,53 @@ func (q *Queries) RemoveFollowForUser(ctx context.Context, arg RemoveFollowForUs
 	_, err := q.db.ExecContext(ctx, removeFollowForUser, arg.UserID, arg.FeedID)
 	return err
 }
+
+const setFeedBodyHash = `-- name: SetFeedBodyHash :exec
+UPDATE feed
+SET last_body_hash = $2
+WHERE id = $1
+`
+
+type SetFeedBodyHashParams struct {
+	ID           uuid.UUID
+	LastBodyHash sql.NullString
+}
+
+func (q *Queries) SetFeedBodyHash(ctx context.Context, arg SetFeedBodyHashParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedBodyHash, arg.ID, arg.LastBodyHash)
+	return err
+}
+
+const setFeedFirstFetchedAt = `-- name: SetFeedFirstFetchedAt :exec
+UPDATE feed
+SET first_fetched_at = $2
+WHERE id = $1 AND first_fetched_at IS NULL
+`
+
+type SetFeedFirstFetchedAtParams struct {
+	ID             uuid.UUID
+	FirstFetchedAt sql.NullTime
+}
+
+// Only the first successful fetch is kept, it decides whether the first fetch policy applies
+func (q *Queries) SetFeedFirstFetchedAt(ctx context.Context, arg SetFeedFirstFetchedAtParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFirstFetchedAt, arg.ID, arg.FirstFetchedAt)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feed
+SET retention = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID        uuid.UUID
+	Retention sql.NullString
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention, arg.ID, arg.Retention, arg.UpdatedAt)
+	return err
+}
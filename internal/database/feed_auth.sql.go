@@ -0,0 +1,130 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: feed_auth.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const clearFeedAuth = `-- name: ClearFeedAuth :exec
+DELETE FROM
+feed_auth
+WHERE
+feed_auth.feed_id = $1
+`
+
+func (q *Queries) ClearFeedAuth(ctx context.Context, feedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearFeedAuth, feedID)
+	return err
+}
+
+const deleteFeedAuthKind = `-- name: DeleteFeedAuthKind :exec
+DELETE FROM
+feed_auth
+WHERE
+feed_auth.feed_id = $1 AND
+feed_auth.kind = $2
+`
+
+type DeleteFeedAuthKindParams struct {
+	FeedID uuid.UUID
+	Kind   string
+}
+
+func (q *Queries) DeleteFeedAuthKind(ctx context.Context, arg DeleteFeedAuthKindParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedAuthKind, arg.FeedID, arg.Kind)
+	return err
+}
+
+const getFeedAuth = `-- name: GetFeedAuth :many
+SELECT
+    id, created_at, updated_at, feed_id, kind, name, value
+FROM feed_auth
+WHERE feed_id = $1
+ORDER BY kind, name
+`
+
+func (q *Queries) GetFeedAuth(ctx context.Context, feedID uuid.UUID) ([]FeedAuth, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedAuth, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedAuth
+	for rows.Next() {
+		var i FeedAuth
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Kind,
+			&i.Name,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedAuth = `-- name: SetFeedAuth :one
+INSERT INTO feed_auth(created_at, updated_at, feed_id, kind, name, value)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (feed_id, kind, name) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    value = EXCLUDED.value
+RETURNING id, created_at, updated_at, feed_id, kind, name, value
+`
+
+type SetFeedAuthParams struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	Kind      string
+	Name      string
+	Value     []byte
+}
+
+func (q *Queries) SetFeedAuth(ctx context.Context, arg SetFeedAuthParams) (FeedAuth, error) {
+	row := q.db.QueryRowContext(ctx, setFeedAuth,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.Kind,
+		arg.Name,
+		arg.Value,
+	)
+	var i FeedAuth
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.Kind,
+		&i.Name,
+		&i.Value,
+	)
+	return i, err
+}
@@ -0,0 +1,191 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: feed_fetches.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFeedFetch = `-- name: CreateFeedFetch :one
+INSERT INTO feed_fetches(
+    feed_id,
+    started_at,
+    duration_ms,
+    http_status,
+    bytes,
+    items_seen,
+    items_inserted,
+    error,
+    unchanged
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING id, feed_id, started_at, duration_ms, http_status, bytes, items_seen, items_inserted, error, unchanged
+`
+
+type CreateFeedFetchParams struct {
+	FeedID        uuid.UUID
+	StartedAt     time.Time
+	DurationMs    int32
+	HttpStatus    sql.NullInt32
+	Bytes         int64
+	ItemsSeen     int32
+	ItemsInserted int32
+	Error         sql.NullString
+	Unchanged     bool
+}
+
+func (q *Queries) CreateFeedFetch(ctx context.Context, arg CreateFeedFetchParams) (FeedFetch, error) {
+	row := q.db.QueryRowContext(ctx, createFeedFetch,
+		arg.FeedID,
+		arg.StartedAt,
+		arg.DurationMs,
+		arg.HttpStatus,
+		arg.Bytes,
+		arg.ItemsSeen,
+		arg.ItemsInserted,
+		arg.Error,
+		arg.Unchanged,
+	)
+	var i FeedFetch
+	err := row.Scan(
+		&i.ID,
+		&i.FeedID,
+		&i.StartedAt,
+		&i.DurationMs,
+		&i.HttpStatus,
+		&i.Bytes,
+		&i.ItemsSeen,
+		&i.ItemsInserted,
+		&i.Error,
+		&i.Unchanged,
+	)
+	return i, err
+}
+
+const getFeedFetchStats = `-- name: GetFeedFetchStats :many
+SELECT
+    f.id,
+    f.name,
+    f.url,
+    f.last_fetched_at,
+    COUNT(ff.id) AS fetches,
+    COUNT(ff.id) FILTER (WHERE ff.error IS NULL) AS successes,
+    COUNT(ff.id) FILTER (WHERE ff.unchanged) AS unchanged,
+    CAST(COALESCE(AVG(ff.duration_ms), 0) AS DOUBLE PRECISION) AS avg_duration_ms,
+    CAST(COALESCE(MAX(ff.duration_ms), 0) AS INTEGER) AS max_duration_ms
+FROM feed AS f
+LEFT JOIN feed_fetches AS ff
+ON ff.feed_id = f.id AND ff.started_at >= $1
+GROUP BY f.id, f.name, f.url, f.last_fetched_at
+ORDER BY f.name
+`
+
+type GetFeedFetchStatsRow struct {
+	ID            uuid.UUID
+	Name          string
+	Url           string
+	LastFetchedAt sql.NullTime
+	Fetches       int64
+	Successes     int64
+	Unchanged     int64
+	AvgDurationMs float64
+	MaxDurationMs int32
+}
+
+func (q *Queries) GetFeedFetchStats(ctx context.Context, startedAt time.Time) ([]GetFeedFetchStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFetchStats, startedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFetchStatsRow
+	for rows.Next() {
+		var i GetFeedFetchStatsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.LastFetchedAt,
+			&i.Fetches,
+			&i.Successes,
+			&i.Unchanged,
+			&i.AvgDurationMs,
+			&i.MaxDurationMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentFeedFetches = `-- name: GetRecentFeedFetches :many
+SELECT
+    id, feed_id, started_at, duration_ms, http_status, bytes, items_seen, items_inserted, error, unchanged
+FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY started_at DESC
+LIMIT $2
+`
+
+type GetRecentFeedFetchesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentFeedFetches(ctx context.Context, arg GetRecentFeedFetchesParams) ([]FeedFetch, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentFeedFetches, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFetch
+	for rows.Next() {
+		var i FeedFetch
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.StartedAt,
+			&i.DurationMs,
+			&i.HttpStatus,
+			&i.Bytes,
+			&i.ItemsSeen,
+			&i.ItemsInserted,
+			&i.Error,
+			&i.Unchanged,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,104 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: feed_responses.sql
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFeedResponse = `-- name: CreateFeedResponse :exec
+INSERT INTO feed_responses(feed_id, fetched_at, http_status, headers, body)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+`
+
+type CreateFeedResponseParams struct {
+	FeedID     uuid.UUID
+	FetchedAt  time.Time
+	HttpStatus int32
+	Headers    json.RawMessage
+	Body       []byte
+}
+
+func (q *Queries) CreateFeedResponse(ctx context.Context, arg CreateFeedResponseParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedResponse,
+		arg.FeedID,
+		arg.FetchedAt,
+		arg.HttpStatus,
+		arg.Headers,
+		arg.Body,
+	)
+	return err
+}
+
+const getFeedResponses = `-- name: GetFeedResponses :many
+SELECT
+    id, feed_id, fetched_at, http_status, headers, body
+FROM feed_responses
+WHERE feed_id = $1
+ORDER BY fetched_at ASC
+`
+
+func (q *Queries) GetFeedResponses(ctx context.Context, feedID uuid.UUID) ([]FeedResponse, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedResponses, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedResponse
+	for rows.Next() {
+		var i FeedResponse
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.FetchedAt,
+			&i.HttpStatus,
+			&i.Headers,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pruneFeedResponses = `-- name: PruneFeedResponses :exec
+DELETE FROM feed_responses
+WHERE feed_responses.feed_id = $1
+AND feed_responses.id NOT IN (
+    SELECT kept.id
+    FROM feed_responses AS kept
+    WHERE kept.feed_id = $1
+    ORDER BY kept.fetched_at DESC
+    LIMIT $2
+)
+`
+
+type PruneFeedResponsesParams struct {
+	FeedID uuid.UUID
+	Keep   int32
+}
+
+func (q *Queries) PruneFeedResponses(ctx context.Context, arg PruneFeedResponsesParams) error {
+	_, err := q.db.ExecContext(ctx, pruneFeedResponses, arg.FeedID, arg.Keep)
+	return err
+}
@@ -0,0 +1,98 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: follow_tags.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const getFollowTagsForUser = `-- name: GetFollowTagsForUser :many
+SELECT
+    ff.feed_id,
+    ft.tag
+FROM follow_tags AS ft
+INNER JOIN feed_follows AS ff
+ON ff.id = ft.feed_follow_id
+INNER JOIN feed AS f
+ON f.id = ff.feed_id
+WHERE ff.user_id = $1
+ORDER BY ft.tag, f.name
+`
+
+type GetFollowTagsForUserRow struct {
+	FeedID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) GetFollowTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetFollowTagsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowTagsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowTagsForUserRow
+	for rows.Next() {
+		var i GetFollowTagsForUserRow
+		if err := rows.Scan(&i.FeedID, &i.Tag); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tagFollow = `-- name: TagFollow :exec
+INSERT INTO follow_tags(feed_follow_id, tag)
+SELECT
+    ff.id,
+    $3
+FROM feed_follows AS ff
+WHERE ff.user_id = $1 AND ff.feed_id = $2
+ON CONFLICT (feed_follow_id, tag) DO NOTHING
+`
+
+type TagFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) TagFollow(ctx context.Context, arg TagFollowParams) error {
+	_, err := q.db.ExecContext(ctx, tagFollow, arg.UserID, arg.FeedID, arg.Tag)
+	return err
+}
+
+const untagFollow = `-- name: UntagFollow :execrows
+DELETE FROM follow_tags
+WHERE tag = $3
+AND feed_follow_id IN (
+    SELECT ff.id
+    FROM feed_follows AS ff
+    WHERE ff.user_id = $1 AND ff.feed_id = $2
+)
+`
+
+type UntagFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) UntagFollow(ctx context.Context, arg UntagFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagFollow, arg.UserID, arg.FeedID, arg.Tag)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// Drivers gator can store its data with
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Settings applied to every SQLite connection: enforce foreign keys, wait for other writers
+// instead of failing straight away and take the write lock when a transaction starts
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// Driver returns the driver and data source for a db_url. sqlite://<path> (or file:<path>) selects
+// SQLite, anything else is handed to Postgres
+func Driver(dbURL string) (string, string, error) {
+	var path string
+	switch {
+	case strings.HasPrefix(dbURL, "sqlite://"):
+		path = strings.TrimPrefix(dbURL, "sqlite://")
+	case strings.HasPrefix(dbURL, "sqlite:"):
+		path = strings.TrimPrefix(dbURL, "sqlite:")
+	case strings.HasPrefix(dbURL, "file:"):
+		path = strings.TrimPrefix(dbURL, "file:")
+	default:
+		return DriverPostgres, dbURL, nil
+	}
+
+	path, params, _ := strings.Cut(path, "?")
+	if path == "" {
+		return "", "", fmt.Errorf("db_url %q is missing the path of the SQLite database", dbURL)
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		path = filepath.Join(homeDir, path[2:])
+	}
+
+	if params != "" {
+		params += "&"
+	}
+	return DriverSQLite, "file:" + path + "?" + params + sqliteOptions, nil
+}
+
+// Open connects to the database in dbURL and returns the store to run queries on
+func Open(dbURL string) (*sql.DB, Store, error) {
+	driver, dataSource, err := Driver(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if driver != DriverSQLite {
+		return db, &sqlStore{Queries: New(db), db: db}, nil
+	}
+
+	sqliteQueries, err := loadSQLiteQueries()
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, &sqlStore{Queries: New(sqliteDB{db: db, queries: sqliteQueries}), db: db, sqliteQueries: sqliteQueries}, nil
+}
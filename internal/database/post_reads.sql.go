@@ -0,0 +1,175 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: post_reads.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const findPostsForUser = `-- name: FindPostsForUser :many
+SELECT
+    p.id,
+    p.title,
+    p.url
+FROM posts AS p
+WHERE (
+    p.feed_id IN (
+        SELECT ff.feed_id
+        FROM feed_follows AS ff
+        WHERE ff.user_id = $1
+    )
+    OR EXISTS (
+        SELECT 1
+        FROM post_stars AS ps
+        WHERE ps.post_id = p.id AND ps.user_id = $1
+    )
+)
+AND (
+    p.url = $2
+    OR substr(CAST(p.id AS TEXT), 1, length($2)) = $2
+)
+ORDER BY p.published_at DESC
+LIMIT 2
+`
+
+type FindPostsForUserParams struct {
+	UserID uuid.UUID
+	Ref    string
+}
+
+type FindPostsForUserRow struct {
+	ID    uuid.UUID
+	Title string
+	Url   string
+}
+
+// ref is the url of a post or the start of its id, as shown by browse. Only posts of feeds the user
+// follows or posts they starred are found, and at most two so an ambiguous id can be told apart
+// The id is compared as a plain prefix, LIKE would treat % and _ in ref as wildcards
+func (q *Queries) FindPostsForUser(ctx context.Context, arg FindPostsForUserParams) ([]FindPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, findPostsForUser, arg.UserID, arg.Ref)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FindPostsForUserRow
+	for rows.Next() {
+		var i FindPostsForUserRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedPostsReadForFollowers = `-- name: MarkFeedPostsReadForFollowers :exec
+INSERT INTO post_reads(user_id, post_id, read_at)
+SELECT
+    ff.user_id,
+    p.id,
+    $2
+FROM posts AS p
+INNER JOIN feed_follows AS ff
+ON ff.feed_id = p.feed_id
+WHERE p.feed_id = $1
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkFeedPostsReadForFollowersParams struct {
+	FeedID uuid.UUID
+	ReadAt time.Time
+}
+
+func (q *Queries) MarkFeedPostsReadForFollowers(ctx context.Context, arg MarkFeedPostsReadForFollowersParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedPostsReadForFollowers, arg.FeedID, arg.ReadAt)
+	return err
+}
+
+const markPostRead = `-- name: MarkPostRead :exec
+INSERT INTO post_reads(user_id, post_id, read_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkPostReadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID, arg.ReadAt)
+	return err
+}
+
+const markPostUnread = `-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2
+`
+
+type MarkPostUnreadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostUnread, arg.UserID, arg.PostID)
+	return err
+}
+
+const markPostsRead = `-- name: MarkPostsRead :execrows
+INSERT INTO post_reads(user_id, post_id, read_at)
+SELECT
+    $1,
+    p.id,
+    $2
+FROM posts AS p
+WHERE p.feed_id IN (
+    SELECT ff.feed_id
+    FROM feed_follows AS ff
+    WHERE ff.user_id = $1
+)
+AND ($3::UUID IS NULL OR p.feed_id = $3)
+AND ($4::TIMESTAMP IS NULL OR p.published_at < $4)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkPostsReadParams struct {
+	UserID          uuid.UUID
+	ReadAt          time.Time
+	FeedID          uuid.NullUUID
+	PublishedBefore sql.NullTime
+}
+
+// Marks the posts of the feeds the user follows as read, optionally only those of one feed and
+// those published before a time
+func (q *Queries) MarkPostsRead(ctx context.Context, arg MarkPostsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostsRead,
+		arg.UserID,
+		arg.ReadAt,
+		arg.FeedID,
+		arg.PublishedBefore,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
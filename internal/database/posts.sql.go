@@ -11,9 +11,44 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :one
+const countPrunablePosts = `-- name: CountPrunablePosts :one
+SELECT
+    COUNT(*)
+FROM posts AS p
+WHERE p.feed_id = $1
+AND NOT EXISTS (
+    SELECT 1
+    FROM post_stars AS ps
+    WHERE ps.post_id = p.id
+)
+AND ($2::TIMESTAMP IS NULL OR p.published_at < $2)
+AND ($3::INTEGER IS NULL OR p.id NOT IN (
+    SELECT latest.id
+    FROM posts AS latest
+    WHERE latest.feed_id = $1
+    ORDER BY latest.published_at DESC, latest.created_at DESC
+    LIMIT $3
+))
+`
+
+type CountPrunablePostsParams struct {
+	FeedID          uuid.UUID
+	PublishedBefore sql.NullTime
+	KeepLatest      sql.NullInt32
+}
+
+// Counts what PrunePosts would delete, for prune --dry-run
+func (q *Queries) CountPrunablePosts(ctx context.Context, arg CountPrunablePostsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPrunablePosts, arg.FeedID, arg.PublishedBefore, arg.KeepLatest)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createPosts = `-- name: CreatePosts :many
 INSERT INTO posts(
     created_at,
     updated_at,
@@ -21,82 +56,158 @@ INSERT INTO posts(
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    content
 )
-VALUES (
+SELECT
+    $1,
     $1,
+    item.title,
+    item.url,
+    NULLIF(item.description, ''),
+    item.published_at,
     $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+    NULLIF(item.content, '')
+FROM (
+    -- unnest calls side by side in a select list are zipped together
+    SELECT
+        unnest($3::TEXT[]) AS title,
+        unnest($4::TEXT[]) AS url,
+        unnest($5::TEXT[]) AS description,
+        unnest($6::TIMESTAMP[]) AS published_at,
+        unnest($7::TEXT[]) AS content
+) AS item
+ON CONFLICT (url) DO NOTHING
+RETURNING id, title, url, published_at
 `
 
-type CreatePostParams struct {
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+type CreatePostsParams struct {
+	Now          time.Time
+	FeedID       uuid.UUID
+	Titles       []string
+	Urls         []string
+	Descriptions []string
+	PublishedAts []time.Time
+	Contents     []string
+}
+
+type CreatePostsRow struct {
+	ID          uuid.UUID
 	Title       string
 	Url         string
-	Description sql.NullString
 	PublishedAt time.Time
-	FeedID      uuid.UUID
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
+// Inserts the items of one fetch in a single statement, each a position in the arrays. Posts already
+// stored are skipped, so only the new ones are returned
+func (q *Queries) CreatePosts(ctx context.Context, arg CreatePostsParams) ([]CreatePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, createPosts,
+		arg.Now,
 		arg.FeedID,
+		pq.Array(arg.Titles),
+		pq.Array(arg.Urls),
+		pq.Array(arg.Descriptions),
+		pq.Array(arg.PublishedAts),
+		pq.Array(arg.Contents),
 	)
-	var i Post
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Title,
-		&i.Url,
-		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
-	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CreatePostsRow
+	for rows.Next() {
+		var i CreatePostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestPostSeq = `-- name: GetLatestPostSeq :one
+SELECT
+    CAST(COALESCE(MAX(seq), 0) AS BIGINT) AS seq
+FROM posts
+`
+
+func (q *Queries) GetLatestPostSeq(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLatestPostSeq)
+	var seq int64
+	err := row.Scan(&seq)
+	return seq, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
 SELECT 
+    p.id,
     p.title,
     p.url,
     p.description,
-    p.published_at
+    p.published_at,
+    EXISTS (
+        SELECT 1
+        FROM post_reads AS pr
+        WHERE pr.post_id = p.id AND pr.user_id = ff.user_id
+    ) AS read,
+    EXISTS (
+        SELECT 1
+        FROM post_stars AS ps
+        WHERE ps.post_id = p.id AND ps.user_id = ff.user_id
+    ) AS starred
 FROM posts as p
 INNER JOIN feed_follows as ff
 ON ff.feed_id = p.feed_id
 WHERE ff.user_id = $1
+AND (NOT $2::BOOLEAN OR NOT EXISTS (
+    SELECT 1
+    FROM post_reads AS pr
+    WHERE pr.post_id = p.id AND pr.user_id = ff.user_id
+))
+AND ($3::TEXT IS NULL OR EXISTS (
+    SELECT 1
+    FROM follow_tags AS ft
+    WHERE ft.feed_follow_id = ff.id AND ft.tag = $3
+))
 ORDER BY p.published_at DESC
-LIMIT $2
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID      uuid.UUID
+	UnreadOnly  bool
+	Folder      sql.NullString
+	ResultLimit int32
 }
 
 type GetPostsForUserRow struct {
+	ID          uuid.UUID
 	Title       string
 	Url         string
 	Description sql.NullString
 	PublishedAt time.Time
+	Read        bool
+	Starred     bool
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.UnreadOnly,
+		arg.Folder,
+		arg.ResultLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -105,10 +216,75 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	for rows.Next() {
 		var i GetPostsForUserRow
 		if err := rows.Scan(
+			&i.ID,
 			&i.Title,
 			&i.Url,
 			&i.Description,
 			&i.PublishedAt,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUserSince = `-- name: GetPostsForUserSince :many
+SELECT
+    p.id,
+    p.seq,
+    p.title,
+    p.url,
+    p.published_at,
+    f.name AS feed_name
+FROM posts as p
+INNER JOIN feed_follows as ff
+ON ff.feed_id = p.feed_id
+INNER JOIN feed as f
+ON f.id = p.feed_id
+WHERE ff.user_id = $1 AND p.seq > $2
+ORDER BY p.seq ASC
+`
+
+type GetPostsForUserSinceParams struct {
+	UserID   uuid.UUID
+	AfterSeq int64
+}
+
+type GetPostsForUserSinceRow struct {
+	ID          uuid.UUID
+	Seq         int64
+	Title       string
+	Url         string
+	PublishedAt time.Time
+	FeedName    string
+}
+
+// seq only grows, so unlike a time it works as a cursor whatever the clocks of the hosts say
+func (q *Queries) GetPostsForUserSince(ctx context.Context, arg GetPostsForUserSinceParams) ([]GetPostsForUserSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserSince, arg.UserID, arg.AfterSeq)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserSinceRow
+	for rows.Next() {
+		var i GetPostsForUserSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Seq,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
 		); err != nil {
 			return nil, err
 		}
@@ -122,3 +298,184 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const prunePosts = `-- name: PrunePosts :execrows
+DELETE FROM posts AS p
+WHERE p.feed_id = $1
+AND NOT EXISTS (
+    SELECT 1
+    FROM post_stars AS ps
+    WHERE ps.post_id = p.id
+)
+AND ($2::TIMESTAMP IS NULL OR p.published_at < $2)
+AND ($3::INTEGER IS NULL OR p.id NOT IN (
+    SELECT latest.id
+    FROM posts AS latest
+    WHERE latest.feed_id = $1
+    ORDER BY latest.published_at DESC, latest.created_at DESC
+    LIMIT $3
+))
+`
+
+type PrunePostsParams struct {
+	FeedID          uuid.UUID
+	PublishedBefore sql.NullTime
+	KeepLatest      sql.NullInt32
+}
+
+// Deletes the posts of a feed published before published_before, or all but the keep_latest newest.
+// Starred posts are never deleted
+func (q *Queries) PrunePosts(ctx context.Context, arg PrunePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, prunePosts, arg.FeedID, arg.PublishedBefore, arg.KeepLatest)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+    p.id,
+    p.url,
+    p.published_at,
+    f.name AS feed_name,
+    CAST(ts_headline(
+        'english',
+        p.title,
+        websearch_to_tsquery('english', $1),
+        'HighlightAll=true, StartSel=' || $2::TEXT || ', StopSel=' || $3::TEXT
+    ) AS TEXT) AS title,
+    CAST(ts_headline(
+        'english',
+        concat_ws(' ', p.description, p.content),
+        websearch_to_tsquery('english', $1),
+        'MaxFragments=2, MaxWords=20, MinWords=8, StartSel=' || $2::TEXT || ', StopSel=' || $3::TEXT
+    ) AS TEXT) AS snippet,
+    CAST(ts_rank(p.search_vector, websearch_to_tsquery('english', $1)) AS DOUBLE PRECISION) AS score
+FROM posts AS p
+INNER JOIN feed AS f
+ON f.id = p.feed_id
+WHERE p.search_vector @@ websearch_to_tsquery('english', $1)
+AND ($4::BOOLEAN OR EXISTS (
+    SELECT 1
+    FROM feed_follows AS ff
+    WHERE ff.feed_id = p.feed_id AND ff.user_id = $5
+))
+ORDER BY score DESC, p.published_at DESC
+LIMIT $6
+`
+
+type SearchPostsParams struct {
+	Query       string
+	StartSel    string
+	StopSel     string
+	AllFeeds    bool
+	UserID      uuid.UUID
+	ResultLimit int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Url         string
+	PublishedAt time.Time
+	FeedName    string
+	Title       string
+	Snippet     string
+	Score       float64
+}
+
+// query uses websearch syntax: "quoted phrases", -excluded words and or between alternatives.
+// Matches in the title and snippet are wrapped in start_sel and stop_sel
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.StartSel,
+		arg.StopSel,
+		arg.AllFeeds,
+		arg.UserID,
+		arg.ResultLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Title,
+			&i.Snippet,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertPost = `-- name: UpsertPost :exec
+INSERT INTO posts(
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+ON CONFLICT (url) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at,
+    content = EXCLUDED.content
+WHERE posts.feed_id = EXCLUDED.feed_id
+`
+
+type UpsertPostParams struct {
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Content     sql.NullString
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) error {
+	_, err := q.db.ExecContext(ctx, upsertPost,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Content,
+	)
+	return err
+}
@@ -0,0 +1,98 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	ActivateWebSubSubscription(ctx context.Context, arg ActivateWebSubSubscriptionParams) error
+	ClaimDueFeed(ctx context.Context, arg ClaimDueFeedParams) (Feed, error)
+	ClaimFeed(ctx context.Context, arg ClaimFeedParams) (Feed, error)
+	// Leases are set and compared by the database clock in UTC, so instances with skewed clocks or other
+	// time zones agree on when one runs out
+	ClaimNextFeedToFetch(ctx context.Context, arg ClaimNextFeedToFetchParams) (Feed, error)
+	ClearFeedAuth(ctx context.Context, feedID uuid.UUID) error
+	CountAdmins(ctx context.Context) (int64, error)
+	CountOtherFollowers(ctx context.Context, arg CountOtherFollowersParams) (int64, error)
+	// Counts what PrunePosts would delete, for prune --dry-run
+	CountPrunablePosts(ctx context.Context, arg CountPrunablePostsParams) (int64, error)
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	CreateFeedFetch(ctx context.Context, arg CreateFeedFetchParams) (FeedFetch, error)
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	CreateFeedResponse(ctx context.Context, arg CreateFeedResponseParams) error
+	// Inserts the items of one fetch in a single statement, each a position in the arrays. Posts already
+	// stored are skipped, so only the new ones are returned
+	CreatePosts(ctx context.Context, arg CreatePostsParams) ([]CreatePostsRow, error)
+	// The first user registered is an admin
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	// Its posts, follows and everything else referring to it are deleted with it
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteFeedAuthKind(ctx context.Context, arg DeleteFeedAuthKindParams) error
+	// ref is the url of a post or the start of its id, as shown by browse. Only posts of feeds the user
+	// follows or posts they starred are found, and at most two so an ambiguous id can be told apart
+	// The id is compared as a plain prefix, LIKE would treat % and _ in ref as wildcards
+	FindPostsForUser(ctx context.Context, arg FindPostsForUserParams) ([]FindPostsForUserRow, error)
+	GetAllFeeds(ctx context.Context) ([]Feed, error)
+	GetFeedAuth(ctx context.Context, feedID uuid.UUID) ([]FeedAuth, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeedByUrl(ctx context.Context, url string) (GetFeedByUrlRow, error)
+	GetFeedFetchStats(ctx context.Context, startedAt time.Time) ([]GetFeedFetchStatsRow, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	GetFeedResponses(ctx context.Context, feedID uuid.UUID) ([]FeedResponse, error)
+	GetFeeds(ctx context.Context) ([]GetFeedsRow, error)
+	GetFeedsByUrlOrName(ctx context.Context, url string) ([]Feed, error)
+	GetFollowTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetFollowTagsForUserRow, error)
+	GetLatestPostSeq(ctx context.Context) (int64, error)
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error)
+	// seq only grows, so unlike a time it works as a cursor whatever the clocks of the hosts say
+	GetPostsForUserSince(ctx context.Context, arg GetPostsForUserSinceParams) ([]GetPostsForUserSinceRow, error)
+	GetRecentFeedFetches(ctx context.Context, arg GetRecentFeedFetchesParams) ([]FeedFetch, error)
+	// Starred posts stay listed after their feed is unfollowed
+	GetStarredPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetStarredPostsForUserRow, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error)
+	GetWebSubSubscriptionByFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error)
+	GetWebSubSubscriptionsToRenew(ctx context.Context, leaseExpiresAt sql.NullTime) ([]WebsubSubscription, error)
+	MarkFeedPostsReadForFollowers(ctx context.Context, arg MarkFeedPostsReadForFollowersParams) error
+	MarkPostRead(ctx context.Context, arg MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) error
+	// Marks the posts of the feeds the user follows as read, optionally only those of one feed and
+	// those published before a time
+	MarkPostsRead(ctx context.Context, arg MarkPostsReadParams) (int64, error)
+	Notify(ctx context.Context, arg NotifyParams) error
+	PruneFeedResponses(ctx context.Context, arg PruneFeedResponsesParams) error
+	// Deletes the posts of a feed published before published_before, or all but the keep_latest newest.
+	// Starred posts are never deleted
+	PrunePosts(ctx context.Context, arg PrunePostsParams) (int64, error)
+	// last_fetched_at is set after every attempt, failed ones included, so feeds are claimed in turn
+	ReleaseFeedLease(ctx context.Context, arg ReleaseFeedLeaseParams) error
+	RemoveFollowForUser(ctx context.Context, arg RemoveFollowForUserParams) error
+	ResetUsers(ctx context.Context) error
+	// query uses websearch syntax: "quoted phrases", -excluded words and or between alternatives.
+	// Matches in the title and snippet are wrapped in start_sel and stop_sel
+	SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error)
+	SetFeedAuth(ctx context.Context, arg SetFeedAuthParams) (FeedAuth, error)
+	SetFeedBodyHash(ctx context.Context, arg SetFeedBodyHashParams) error
+	// Only the first successful fetch is kept, it decides whether the first fetch policy applies
+	SetFeedFirstFetchedAt(ctx context.Context, arg SetFeedFirstFetchedAtParams) error
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error
+	SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error)
+	SetWebSubSubscriptionState(ctx context.Context, arg SetWebSubSubscriptionStateParams) error
+	StarPost(ctx context.Context, arg StarPostParams) error
+	TagFollow(ctx context.Context, arg TagFollowParams) error
+	UnstarPost(ctx context.Context, arg UnstarPostParams) error
+	UntagFollow(ctx context.Context, arg UntagFollowParams) (int64, error)
+	UpsertPost(ctx context.Context, arg UpsertPostParams) error
+	UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error)
+}
+
+var _ Querier = (*Queries)(nil)
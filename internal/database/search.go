@@ -0,0 +1,84 @@
+package database
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchTerm is a word or quoted phrase of a search query
+type SearchTerm struct {
+	Text string
+	// Excluded terms must not appear in a match
+	Excluded bool
+}
+
+// ParseSearchQuery splits a query written in the syntax of Postgres' websearch_to_tsquery: words and
+// "quoted phrases" must all appear, a leading - excludes a term and or separates alternatives.
+// It returns the alternatives, each a list of terms that all have to match
+func ParseSearchQuery(query string) [][]SearchTerm {
+	var alternatives [][]SearchTerm
+	var terms []SearchTerm
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		excluded := false
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			excluded = true
+			i++
+		}
+
+		var text string
+		quoted := runes[i] == '"'
+		if quoted {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			text = string(runes[i+1 : end])
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			text = string(runes[i:end])
+			i = end
+		}
+
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if !quoted && !excluded && strings.EqualFold(text, "or") {
+			if len(terms) > 0 {
+				alternatives = append(alternatives, terms)
+				terms = nil
+			}
+			continue
+		}
+		terms = append(terms, SearchTerm{Text: text, Excluded: excluded})
+	}
+
+	if len(terms) > 0 {
+		alternatives = append(alternatives, terms)
+	}
+	return alternatives
+}
+
+// Searchable reports whether a parsed query can match anything: an alternative made up of only
+// excluded terms doesn't say what to look for
+func Searchable(alternatives [][]SearchTerm) bool {
+	for _, terms := range alternatives {
+		for _, term := range terms {
+			if !term.Excluded {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query      string
+		want       [][]SearchTerm
+		searchable bool
+	}{
+		{query: "", want: nil},
+		{query: "postgres vacuum", want: [][]SearchTerm{{{Text: "postgres"}, {Text: "vacuum"}}}, searchable: true},
+		{query: `"dead rows" -mysql`, want: [][]SearchTerm{{{Text: "dead rows"}, {Text: "mysql", Excluded: true}}}, searchable: true},
+		{query: "vacuum OR purge", want: [][]SearchTerm{{{Text: "vacuum"}}, {{Text: "purge"}}}, searchable: true},
+		// A quoted or excluded or is a term, a dangling or is dropped
+		{query: `or "or" -or`, want: [][]SearchTerm{{{Text: "or"}, {Text: "or", Excluded: true}}}, searchable: true},
+		{query: "vacuum - tuning", want: [][]SearchTerm{{{Text: "vacuum"}, {Text: "-"}, {Text: "tuning"}}}, searchable: true},
+		{query: `"unterminated phrase`, want: [][]SearchTerm{{{Text: "unterminated phrase"}}}, searchable: true},
+		{query: "-mysql", want: [][]SearchTerm{{{Text: "mysql", Excluded: true}}}},
+		{query: `"" -`, want: [][]SearchTerm{{{Text: "-"}}}, searchable: true},
+	}
+	for _, tt := range tests {
+		got := ParseSearchQuery(tt.query)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %+v, got %+v", tt.query, tt.want, got)
+		}
+		if Searchable(got) != tt.searchable {
+			t.Errorf("%q: expected searchable %v", tt.query, tt.searchable)
+		}
+	}
+}
+
+func TestFTS5Query(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "postgres vacuum", want: `("postgres" AND "vacuum")`},
+		{query: `"dead rows" -mysql`, want: `("dead rows" NOT "mysql")`},
+		{query: "vacuum or purge -mysql", want: `("vacuum") OR ("purge" NOT "mysql")`},
+		// FTS5 operators and quotes in terms are matched as text
+		{query: `NEAR(a b) col:x say"hi"`, want: `("NEAR(a" AND "b)" AND "col:x" AND "say" AND "hi")`},
+		{query: `"a ""quoted"" word"`, want: `("a" AND "quoted" AND "word")`},
+		// An alternative of only excluded terms can't be written in FTS5
+		{query: "vacuum or -mysql", want: `("vacuum")`},
+	}
+	for _, tt := range tests {
+		if got := fts5Query(tt.query); got != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.query, tt.want, got)
+		}
+	}
+}
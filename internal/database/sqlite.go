@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/sql/queries"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+)
+
+// sqliteDB runs the queries generated for Postgres on SQLite. Every query is swapped for the query
+// of the same name in sql/queries/sqlite, which takes its arguments in the same order
+type sqliteDB struct {
+	db      DBTX
+	queries map[string]string
+}
+
+func (s sqliteDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	text, err := s.query(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.ExecContext(ctx, text, sqliteArgs(query, args)...)
+}
+
+func (s sqliteDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	text, err := s.query(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.PrepareContext(ctx, text)
+}
+
+func (s sqliteDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	text, err := s.query(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.QueryContext(ctx, text, sqliteArgs(query, args)...)
+}
+
+func (s sqliteDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	text, err := s.query(query)
+	if err != nil {
+		// Only database/sql can make a *sql.Row, so SQLite is asked to fail with the error instead
+		return s.db.QueryRowContext(ctx, "SELECT "+sqliteErrorFunction+"(?1)", err.Error())
+	}
+	return s.db.QueryRowContext(ctx, text, sqliteArgs(query, args)...)
+}
+
+// query looks up the SQLite version of a query by its name. Sending the Postgres text instead would
+// often still run, with its arguments bound wrongly, so a query without one fails
+func (s sqliteDB) query(query string) (string, error) {
+	name := queryName(query)
+	text, ok := s.queries[name]
+	if !ok {
+		return "", fmt.Errorf("no SQLite version of query %q, add it to sql/queries/sqlite", name)
+	}
+	return text, nil
+}
+
+// queryName returns the name from the `-- name: X :kind` line sqlc starts a query with
+func queryName(query string) string {
+	header, _, _ := strings.Cut(query, "\n")
+	fields := strings.Fields(header)
+	if len(fields) < 3 || fields[0] != "--" || fields[1] != "name:" {
+		return ""
+	}
+	return fields[2]
+}
+
+// sqliteErrorFunction fails the statement calling it with its argument as the error
+const sqliteErrorFunction = "gator_error"
+
+var (
+	loadSQLite    sync.Once
+	sqliteQueries map[string]string
+	sqliteErr     error
+)
+
+// loadSQLiteQueries reads the SQLite queries once and checks there is one for every query
+func loadSQLiteQueries() (map[string]string, error) {
+	loadSQLite.Do(func() {
+		sqliteErr = sqlite.RegisterScalarFunction(sqliteErrorFunction, 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			return nil, fmt.Errorf("%v", args[0])
+		})
+		if sqliteErr != nil {
+			return
+		}
+		sqliteQueries, sqliteErr = readSQLiteQueries(queries.SQLite)
+		if sqliteErr != nil {
+			return
+		}
+
+		var missing []string
+		querier := reflect.TypeOf((*Querier)(nil)).Elem()
+		for i := 0; i < querier.NumMethod(); i++ {
+			if name := querier.Method(i).Name; sqliteQueries[name] == "" {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			sqliteErr = fmt.Errorf("no SQLite version of queries %s, add them to sql/queries/sqlite", strings.Join(missing, ", "))
+		}
+	})
+	return sqliteQueries, sqliteErr
+}
+
+// readSQLiteQueries splits the .sql files in files into their queries by the `-- name:` line each starts with
+func readSQLiteQueries(files fs.FS) (map[string]string, error) {
+	paths, err := fs.Glob(files, "sqlite/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make(map[string]string)
+	for _, path := range paths {
+		data, err := fs.ReadFile(files, path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Anything before the first query is a comment on the whole file
+		for _, query := range strings.Split("\n"+string(data), "\n-- name: ")[1:] {
+			text := strings.TrimSpace("-- name: " + query)
+			name := queryName(text)
+			if name == "" {
+				return nil, fmt.Errorf("%s has a query without a name", path)
+			}
+			if _, ok := texts[name]; ok {
+				return nil, fmt.Errorf("%s has a second query called %s", path, name)
+			}
+			texts[name] = text
+		}
+	}
+	return texts, nil
+}
+
+// sqliteQueryArgs adapts the arguments of queries that mean something else to SQLite, by query name
+var sqliteQueryArgs = map[string]func(args []interface{}){
+	// FTS5 has a query syntax of its own
+	"SearchPosts": func(args []interface{}) {
+		if query, ok := args[0].(string); ok {
+			args[0] = fts5Query(query)
+		}
+	},
+}
+
+// sqliteArgs stores times the way a Postgres TIMESTAMP column does: the wall clock time without its
+// zone. Storing everything in one zone also keeps SQLite's text comparisons of times correct.
+// SQLite has no arrays, so Postgres array arguments are passed as JSON arrays for json_each
+func sqliteArgs(query string, args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case time.Time:
+			converted[i] = wallClock(value)
+		case sql.NullTime:
+			if value.Valid {
+				value.Time = wallClock(value.Time)
+			}
+			converted[i] = value
+		case *pq.StringArray, *pq.Int64Array, *pq.Int32Array, *pq.Float64Array, *pq.BoolArray:
+			converted[i] = jsonArray(value)
+		case pq.GenericArray:
+			converted[i] = jsonArray(arrayElements(value.A))
+		default:
+			converted[i] = arg
+		}
+	}
+
+	if adapt, ok := sqliteQueryArgs[queryName(query)]; ok {
+		adapt(converted)
+	}
+	return converted
+}
+
+// fts5Query writes a websearch style query (see ParseSearchQuery) in FTS5 syntax. FTS5 can only exclude
+// terms from something else, so alternatives made up of only excluded terms are left out
+func fts5Query(query string) string {
+	var alternatives []string
+	for _, terms := range ParseSearchQuery(query) {
+		var included, excluded []string
+		for _, term := range terms {
+			// Quoting turns every term into a phrase, which keeps FTS5 operators in it from being interpreted
+			phrase := `"` + strings.ReplaceAll(term.Text, `"`, `""`) + `"`
+			if term.Excluded {
+				excluded = append(excluded, phrase)
+			} else {
+				included = append(included, phrase)
+			}
+		}
+		if len(included) == 0 {
+			continue
+		}
+
+		alternative := strings.Join(included, " AND ")
+		for _, phrase := range excluded {
+			alternative += " NOT " + phrase
+		}
+		alternatives = append(alternatives, "("+alternative+")")
+	}
+	return strings.Join(alternatives, " OR ")
+}
+
+// jsonArray encodes values for json_each
+func jsonArray(values interface{}) string {
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
+// arrayElements converts the elements of a slice passed to pq.Array the way they are stored on their own.
+// Times are written the way the driver writes a time.Time so they compare correctly with the times stored
+// by other queries, other types (e.g. uuid.UUID) by the value they give the driver
+func arrayElements(array interface{}) []interface{} {
+	slice := reflect.ValueOf(array)
+	if slice.Kind() != reflect.Slice {
+		return nil
+	}
+
+	elements := make([]interface{}, slice.Len())
+	for i := range elements {
+		switch value := slice.Index(i).Interface().(type) {
+		case time.Time:
+			elements[i] = wallClock(value).String()
+		case driver.Valuer:
+			elements[i], _ = value.Value()
+		default:
+			elements[i] = value
+		}
+	}
+	return elements
+}
+
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/git-cst/bootdev_gator/sql/migrations"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var errRecorded = errors.New("recorded")
+
+// recordingDB remembers the last query run on it instead of running it
+type recordingDB struct {
+	query string
+	args  []interface{}
+	// conn answers QueryRowContext, as only database/sql can make a *sql.Row
+	conn *sql.DB
+}
+
+func (r *recordingDB) record(query string, args []interface{}) {
+	r.query = query
+	r.args = args
+}
+
+func (r *recordingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.record(query, args)
+	return nil, errRecorded
+}
+
+func (r *recordingDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	r.record(query, nil)
+	return nil, errRecorded
+}
+
+func (r *recordingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.record(query, args)
+	return nil, errRecorded
+}
+
+func (r *recordingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	r.record(query, args)
+	return r.conn.QueryRowContext(ctx, "SELECT 1 WHERE 0")
+}
+
+// openSQLite returns an in-memory SQLite database with every migration applied
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	conn, err := sql.Open(DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	// Every connection to :memory: is a database of its own
+	conn.SetMaxOpenConns(1)
+
+	provider, err := migrations.NewProvider(DriverSQLite, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("could not migrate: %v", err)
+	}
+	return conn
+}
+
+// sqliteParam matches the parameters of a SQLite query, which must all be numbered (?1, ?2, ...)
+var sqliteParam = regexp.MustCompile(`\?(\d*)`)
+
+// TestSQLiteQueryText checks every query has a SQLite version taking the arguments the Postgres one is
+// called with, as sqliteDB binds the Postgres arguments to it by position
+func TestSQLiteQueryText(t *testing.T) {
+	texts, err := loadSQLiteQueries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := openSQLite(t)
+	recorder := &recordingDB{conn: conn}
+	queries := reflect.ValueOf(New(recorder))
+	querier := reflect.TypeOf((*Querier)(nil)).Elem()
+
+	methods := map[string]bool{}
+	for i := 0; i < querier.NumMethod(); i++ {
+		name := querier.Method(i).Name
+		methods[name] = true
+
+		t.Run(name, func(t *testing.T) {
+			// Zero values are enough to see which query is run with how many arguments
+			method := queries.MethodByName(name)
+			args := []reflect.Value{reflect.ValueOf(context.Background())}
+			for j := 1; j < method.Type().NumIn(); j++ {
+				args = append(args, reflect.Zero(method.Type().In(j)))
+			}
+			recorder.record("", nil)
+			method.Call(args)
+
+			if queryName(recorder.query) != name {
+				t.Fatalf("expected query %s to be run, got %q", name, queryName(recorder.query))
+			}
+			text, ok := texts[name]
+			if !ok {
+				t.Fatalf("no SQLite version of %s in sql/queries/sqlite", name)
+			}
+
+			highest := 0
+			for _, match := range sqliteParam.FindAllStringSubmatch(text, -1) {
+				number, err := strconv.Atoi(match[1])
+				if err != nil {
+					t.Fatalf("the SQLite version of %s has an unnumbered parameter %q", name, match[0])
+				}
+				highest = max(highest, number)
+			}
+			if highest != len(recorder.args) {
+				t.Errorf("%s is called with %d arguments but its SQLite version takes %d", name, len(recorder.args), highest)
+			}
+
+			stmt, err := conn.Prepare(text)
+			if err != nil {
+				t.Fatalf("the SQLite version of %s doesn't match the schema: %v", name, err)
+			}
+			stmt.Close()
+		})
+	}
+
+	for name := range texts {
+		if !methods[name] {
+			t.Errorf("sql/queries/sqlite has %s, which isn't a query", name)
+		}
+	}
+}
+
+func TestSQLiteMissingQuery(t *testing.T) {
+	if _, err := loadSQLiteQueries(); err != nil {
+		t.Fatal(err)
+	}
+	queries := New(sqliteDB{db: openSQLite(t), queries: map[string]string{}})
+	ctx := context.Background()
+
+	// A query is run through QueryRowContext, another through ExecContext
+	if _, err := queries.GetUser(ctx, "bob"); err == nil || !strings.Contains(err.Error(), `no SQLite version of query "GetUser"`) {
+		t.Errorf("expected an error naming GetUser, got %v", err)
+	}
+	if err := queries.ResetUsers(ctx); err == nil || !strings.Contains(err.Error(), `no SQLite version of query "ResetUsers"`) {
+		t.Errorf("expected an error naming ResetUsers, got %v", err)
+	}
+}
+
+func TestReadSQLiteQueries(t *testing.T) {
+	files := fstest.MapFS{
+		"sqlite/users.sql": {Data: []byte("-- Users\n\n-- name: GetUser :one\nSELECT * FROM users\nWHERE name = ?1;\n\n-- name: ResetUsers :exec\nDELETE FROM users;\n")},
+	}
+	texts, err := readSQLiteQueries(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"GetUser":    "-- name: GetUser :one\nSELECT * FROM users\nWHERE name = ?1;",
+		"ResetUsers": "-- name: ResetUsers :exec\nDELETE FROM users;",
+	}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("expected %q, got %q", want, texts)
+	}
+
+	files["sqlite/more.sql"] = &fstest.MapFile{Data: []byte("-- name: GetUser :one\nSELECT 1;")}
+	if _, err := readSQLiteQueries(files); err == nil {
+		t.Error("two queries with the same name should fail")
+	}
+}
+
+func TestSQLiteArgsArrays(t *testing.T) {
+	id := uuid.MustParse("6f1c1b9e-59a4-4d8a-9a65-0a3c2c1f7d11")
+	published := time.Date(2024, 1, 31, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name string
+		arg  interface{}
+		want string
+	}{
+		{name: "strings", arg: pq.Array([]string{"a", "b"}), want: `["a","b"]`},
+		{name: "ints", arg: pq.Array([]int64{1, 2}), want: `[1,2]`},
+		{name: "times", arg: pq.Array([]time.Time{published}), want: `["2024-01-31 12:30:00 +0000 UTC"]`},
+		{name: "uuids", arg: pq.Array([]uuid.UUID{id}), want: `["` + id.String() + `"]`},
+		{name: "empty", arg: pq.Array([]string{}), want: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sqliteArgs("-- name: Test :exec", []interface{}{tt.arg})
+			if got[0] != tt.want {
+				t.Errorf("expected %s, got %v", tt.want, got[0])
+			}
+		})
+	}
+}
+
+// TestSQLiteSearchBackfill checks posts saved before the search index existed can be searched
+func TestSQLiteSearchBackfill(t *testing.T) {
+	ctx := context.Background()
+	conn, err := sql.Open(DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetMaxOpenConns(1)
+
+	provider, err := migrations.NewProvider(DriverSQLite, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.UpTo(ctx, 1); err != nil {
+		t.Fatalf("could not migrate to the first version: %v", err)
+	}
+	_, err = conn.ExecContext(ctx, `
+		INSERT INTO users (id, created_at, updated_at, name) VALUES ('u', '2024-01-01', '2024-01-01', 'bob');
+		INSERT INTO feed (id, created_at, updated_at, name, url, user_id) VALUES ('f', '2024-01-01', '2024-01-01', 'Blog', 'https://example.com/rss', 'u');
+		INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+		VALUES ('4a1f0c3e-2b7d-4c8e-9f10-5d6e7a8b9c0d', '2024-01-01', '2024-01-01', 'Postgres vacuum explained', 'https://example.com/vacuum', 'Dead rows', '2024-01-01', 'f');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("could not migrate: %v", err)
+	}
+
+	texts, err := loadSQLiteQueries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := New(sqliteDB{db: conn, queries: texts})
+	for _, query := range []string{"vacuum", `"dead rows"`} {
+		rows, err := queries.SearchPosts(ctx, SearchPostsParams{Query: query, AllFeeds: true, ResultLimit: 10})
+		if err != nil {
+			t.Fatalf("search %s failed: %v", query, err)
+		}
+		if len(rows) != 1 || rows[0].Title != "Postgres vacuum explained" {
+			t.Errorf("search %s: expected the post saved before the index, got %+v", query, rows)
+		}
+	}
+}
+
+// TestSQLiteAdminBackfill checks the earliest user of an existing database becomes its admin
+func TestSQLiteAdminBackfill(t *testing.T) {
+	ctx := context.Background()
+	conn, err := sql.Open(DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetMaxOpenConns(1)
+
+	provider, err := migrations.NewProvider(DriverSQLite, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.UpTo(ctx, 7); err != nil {
+		t.Fatalf("could not migrate to the version before admins: %v", err)
+	}
+	_, err = conn.ExecContext(ctx, `
+		INSERT INTO users (id, created_at, updated_at, name) VALUES
+		('5b2c7d1e-3f4a-4b6c-8d9e-0f1a2b3c4d5e', '2024-02-01', '2024-02-01', 'alice'),
+		('6c3d8e2f-4a5b-4c7d-9e0f-1a2b3c4d5e6f', '2024-01-01', '2024-01-01', 'bob');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Up(ctx); err != nil {
+		t.Fatalf("could not migrate: %v", err)
+	}
+
+	texts, err := loadSQLiteQueries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := New(sqliteDB{db: conn, queries: texts})
+	for name, want := range map[string]bool{"bob": true, "alice": false} {
+		user, err := queries.GetUser(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.IsAdmin != want {
+			t.Errorf("%s: expected admin %v, got %v", name, want, user.IsAdmin)
+		}
+	}
+}
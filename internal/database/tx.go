@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Store is a Querier that can also run several queries as one transaction
+type Store interface {
+	Querier
+	// InTx runs fn in a transaction, which is committed when fn returns nil and rolled back otherwise
+	InTx(ctx context.Context, fn func(Querier) error) error
+}
+
+// sqlStore runs the queries on a database/sql connection
+type sqlStore struct {
+	*Queries
+	db *sql.DB
+	// sqliteQueries is the SQLite version of every query, nil on Postgres
+	sqliteQueries map[string]string
+}
+
+func (s *sqlStore) InTx(ctx context.Context, fn func(Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// WithTx would drop the SQLite translation, so wrap the transaction the same way as the connection
+	queries := s.Queries.WithTx(tx)
+	if s.sqliteQueries != nil {
+		queries = New(sqliteDB{db: tx, queries: s.sqliteQueries})
+	}
+
+	err = fn(queries)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%v (rolling back failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
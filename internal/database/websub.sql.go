@@ -0,0 +1,197 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: websub.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const activateWebSubSubscription = `-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET
+    updated_at = $1,
+    state = 'active',
+    lease_expires_at = $2
+WHERE id = $3
+`
+
+type ActivateWebSubSubscriptionParams struct {
+	UpdatedAt      time.Time
+	LeaseExpiresAt sql.NullTime
+	ID             uuid.UUID
+}
+
+func (q *Queries) ActivateWebSubSubscription(ctx context.Context, arg ActivateWebSubSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, activateWebSubSubscription, arg.UpdatedAt, arg.LeaseExpiresAt, arg.ID)
+	return err
+}
+
+const getWebSubSubscription = `-- name: GetWebSubSubscription :one
+SELECT
+    id, created_at, updated_at, feed_id, hub_url, topic_url, secret, state, lease_expires_at
+FROM websub_subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscription, id)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.State,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionByFeed = `-- name: GetWebSubSubscriptionByFeed :one
+SELECT
+    id, created_at, updated_at, feed_id, hub_url, topic_url, secret, state, lease_expires_at
+FROM websub_subscriptions
+WHERE feed_id = $1
+`
+
+func (q *Queries) GetWebSubSubscriptionByFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscriptionByFeed, feedID)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.State,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionsToRenew = `-- name: GetWebSubSubscriptionsToRenew :many
+SELECT
+    id, created_at, updated_at, feed_id, hub_url, topic_url, secret, state, lease_expires_at
+FROM websub_subscriptions
+WHERE state = 'active' AND lease_expires_at < $1
+`
+
+func (q *Queries) GetWebSubSubscriptionsToRenew(ctx context.Context, leaseExpiresAt sql.NullTime) ([]WebsubSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, getWebSubSubscriptionsToRenew, leaseExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebsubSubscription
+	for rows.Next() {
+		var i WebsubSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.HubUrl,
+			&i.TopicUrl,
+			&i.Secret,
+			&i.State,
+			&i.LeaseExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setWebSubSubscriptionState = `-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET
+    updated_at = $1,
+    state = $2
+WHERE id = $3
+`
+
+type SetWebSubSubscriptionStateParams struct {
+	UpdatedAt time.Time
+	State     string
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetWebSubSubscriptionState(ctx context.Context, arg SetWebSubSubscriptionStateParams) error {
+	_, err := q.db.ExecContext(ctx, setWebSubSubscriptionState, arg.UpdatedAt, arg.State, arg.ID)
+	return err
+}
+
+const upsertWebSubSubscription = `-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions(created_at, updated_at, feed_id, hub_url, topic_url, secret, state)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = EXCLUDED.secret,
+    state = EXCLUDED.state
+RETURNING id, created_at, updated_at, feed_id, hub_url, topic_url, secret, state, lease_expires_at
+`
+
+type UpsertWebSubSubscriptionParams struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	HubUrl    string
+	TopicUrl  string
+	Secret    string
+	State     string
+}
+
+func (q *Queries) UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertWebSubSubscription,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.HubUrl,
+		arg.TopicUrl,
+		arg.Secret,
+		arg.State,
+	)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.Secret,
+		&i.State,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
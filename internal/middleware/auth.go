@@ -1,8 +1,6 @@
 package middleware
 
 import (
-	"context"
-
 	"github.com/git-cst/bootdev_gator/internal/commands"
 	"github.com/git-cst/bootdev_gator/internal/config"
 	"github.com/git-cst/bootdev_gator/internal/database"
@@ -17,7 +15,7 @@ func MiddlewareLoggedIn(handler func(s *config.State, cmd commands.Command, user
 		}
 
 		// Otherwise retrieve from database
-		user, err := s.Db.GetUser(context.Background(), s.Config.User)
+		user, err := s.Db.GetUser(s.Ctx, s.Config.User)
 		if err != nil {
 			s.LogError("Tried to retrieve user %s from database, failed while doing so err: %v", s.Config.User, err)
 			return err
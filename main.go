@@ -1,17 +1,17 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
 	"github.com/git-cst/bootdev_gator/internal/commands"
 	"github.com/git-cst/bootdev_gator/internal/commands/handlers"
 	"github.com/git-cst/bootdev_gator/internal/config"
 	"github.com/git-cst/bootdev_gator/internal/database"
 	"github.com/git-cst/bootdev_gator/internal/middleware"
-	_ "github.com/lib/pq"
 )
 
 func main() {
@@ -21,37 +21,39 @@ func main() {
 		os.Exit(1)
 	}
 
-	db, err := sql.Open("postgres", configFile.DbURL)
+	db, dbQueries, err := database.Open(configFile.DbURL)
 	if err != nil {
 		fmt.Println("Error opening connection to DB:", err)
 		os.Exit(1)
 	}
-	dbQueries := database.New(db)
 
-	// On Unix-like systems (Linux, macOS)
-	username := os.Getenv("USER")
-
-	// On Windows
-	if username == "" {
-		username = os.Getenv("USERNAME")
-	}
-
-	clientSetup := config.ClientOptions{
-		Timeout:     time.Duration(time.Second * 60),
-		UserAgent:   fmt.Sprintf("%s_gator", username),
-		ContentType: "application/rss+xml, application/atom+xml, application/xml, text/xml",
-		Headers:     make(map[string]string),
+	clientSetup, err := configFile.HTTP.ClientOptions()
+	if err != nil {
+		fmt.Println("Error reading http config:", err)
+		os.Exit(1)
 	}
 
 	logger := config.CreateLogger()
 	defer logger.Close()
 
+	// Cancelled on Ctrl-C or SIGTERM (e.g. from systemd)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// Restore the default behaviour so a second signal kills the process straight away
+		<-ctx.Done()
+		stop()
+	}()
+
 	state := config.State{
 		Config: &configFile,
 		Db:     dbQueries,
+		DBConn: db,
 		Logger: logger,
+		Ctx:    ctx,
 	}
 
+	clientSetup.Logf = state.LogInfo
 	state.Config.Client = config.NewClient(clientSetup)
 
 	cmds := commands.Commands{
@@ -63,19 +65,38 @@ func main() {
 	cmds.Register("register", "Register the user in the database.", handlers.HandlerRegister)
 	cmds.Register("reset", "Reset the users table.", handlers.HandlerReset)
 	cmds.Register("users", "Retrieve the available users in the database.", handlers.HandlerUsers)
+	cmds.Register("admin", "Grant or revoke admin rights of a user, admins only: grant|revoke <user>.", middleware.MiddlewareLoggedIn(handlers.HandlerAdmin))
 
 	// rss feed related commands
 	cmds.Register("feeds", "Retrieve the available feeds in the database.", handlers.HandlerGetFeeds)
 	cmds.Register("addfeed", "Add a new feed to be fetched.", middleware.MiddlewareLoggedIn(handlers.HandlerAddFeed))
 	cmds.Register("follow", "Follow a registered feed.", middleware.MiddlewareLoggedIn(handlers.HandlerFollowFeed))
 	cmds.Register("following", "Retrieve what another specified user is following.", middleware.MiddlewareLoggedIn(handlers.HandlerGetFollowing))
+	cmds.Register("tag", "Tag a feed you follow (by url or name) with one or more folders.", middleware.MiddlewareLoggedIn(handlers.HandlerTag))
+	cmds.Register("untag", "Remove one or more folders from a feed you follow (by url or name).", middleware.MiddlewareLoggedIn(handlers.HandlerUntag))
 	cmds.Register("unfollow", "Unfollow a feed.", middleware.MiddlewareLoggedIn(handlers.HandlerUnfollow))
+	cmds.Register("removefeed", "Remove a feed you added (by url) with its posts, --force if others still follow it.", middleware.MiddlewareLoggedIn(handlers.HandlerRemoveFeed))
+	cmds.Register("feedauth", "Manage credentials and headers sent when fetching a feed you added.", middleware.MiddlewareLoggedIn(handlers.HandlerFeedAuth))
+	cmds.Register("retention", "Show or set (forever, latest:<posts>, days:<days> or default) how long a feed you added keeps posts.", middleware.MiddlewareLoggedIn(handlers.HandlerRetention))
 
 	// service related commands
 	cmds.Register("agg", "Start the aggregator service.", handlers.HandlerAgg)
-	cmds.Register("browse", "Browse X feeds where X is the argument passed to the command.", middleware.MiddlewareLoggedIn(handlers.HandlerBrowse))
+	cmds.Register("refresh", "Fetch a feed (by url or name) or --all feeds right now and exit.", handlers.HandlerRefresh)
+	cmds.Register("reparse", "Parse the archived responses of a feed (by url or name) again without fetching it.", handlers.HandlerReparse)
+	cmds.Register("prune", "Delete the posts feeds don't keep per their retention, --dry-run only counts them.", handlers.HandlerPrune)
+	cmds.Register("feedstatus", "Summarise recent fetches per feed, pass a feed url or name for its fetch history.", handlers.HandlerFeedStatus)
+	cmds.Register("browse", "Browse X feeds where X is the argument passed to the command, --unread for unread posts only, --folder <tag> for one folder.", middleware.MiddlewareLoggedIn(handlers.HandlerBrowse))
+	cmds.Register("watch", "Print new posts from the feeds you follow as they arrive.", middleware.MiddlewareLoggedIn(handlers.HandlerWatch))
+	cmds.Register("read", "Mark posts read, by the id shown by browse or their url.", middleware.MiddlewareLoggedIn(handlers.HandlerRead))
+	cmds.Register("unread", "Mark posts unread, by the id shown by browse or their url.", middleware.MiddlewareLoggedIn(handlers.HandlerUnread))
+	cmds.Register("markread", "Mark posts read in bulk: --feed <url or name>, --before <date> or --all.", middleware.MiddlewareLoggedIn(handlers.HandlerMarkRead))
+	cmds.Register("star", "Star posts to keep them on your reading list, by the id shown by browse or their url.", middleware.MiddlewareLoggedIn(handlers.HandlerStar))
+	cmds.Register("unstar", "Remove posts from your starred posts, by the id shown by starred or their url.", middleware.MiddlewareLoggedIn(handlers.HandlerUnstar))
+	cmds.Register("starred", "List the posts you starred, most recently starred first.", middleware.MiddlewareLoggedIn(handlers.HandlerStarred))
+	cmds.Register("search", "Search the posts of the feeds you follow, or --all posts, ranked by relevance.", middleware.MiddlewareLoggedIn(handlers.HandlerSearch))
 
 	// application commands
+	cmds.Register("migrate", "Manage the database schema: up [version], down [version] or status.", handlers.HandlerMigrate)
 	cmds.Register("help", "Display the commands available to you.", cmds.ListCommands)
 
 	state.LogDebug("Starting RSS aggregator application")
@@ -96,6 +117,15 @@ func main() {
 		state.LogDebug("Unauthenticated user executing command: '%s' with args: %v", cmd.Name, cmd.Args)
 	}
 
+	// An outdated schema only leads to confusing query errors, so catch it up front
+	if cmd.Name != "migrate" && cmd.Name != "help" {
+		err = handlers.CheckSchemaVersion(&state)
+		if err != nil {
+			state.LogError("%v", err)
+			os.Exit(1)
+		}
+	}
+
 	err = cmds.Run(&state, cmd)
 	if err != nil {
 		state.LogError("Error executing command '%s': %v", cmd.Name, err)
@@ -0,0 +1,17 @@
+-- +goose up
+-- +goose StatementBegin
+CREATE TABLE feed_auth(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value BYTEA NOT NULL,
+    UNIQUE (feed_id, kind, name),
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose down
+DROP TABLE feed_auth;
\ No newline at end of file
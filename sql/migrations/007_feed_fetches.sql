@@ -0,0 +1,19 @@
+-- +goose up
+-- +goose StatementBegin
+CREATE TABLE feed_fetches(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    feed_id UUID NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    http_status INTEGER NULL,
+    bytes BIGINT NOT NULL,
+    items_seen INTEGER NOT NULL,
+    items_inserted INTEGER NOT NULL,
+    error TEXT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+CREATE INDEX feed_fetches_feed_id_started_at_idx ON feed_fetches(feed_id, started_at);
+-- +goose StatementEnd
+
+-- +goose down
+DROP TABLE feed_fetches;
\ No newline at end of file
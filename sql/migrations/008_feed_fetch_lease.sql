@@ -0,0 +1,9 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE feed
+ADD COLUMN fetch_lease_until TIMESTAMP DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose down
+ALTER TABLE feed
+DROP COLUMN fetch_lease_until;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- +goose up
+-- +goose StatementBegin
+CREATE TABLE websub_subscriptions(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL UNIQUE,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    state TEXT NOT NULL,
+    lease_expires_at TIMESTAMP NULL,
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose down
+DROP TABLE websub_subscriptions;
\ No newline at end of file
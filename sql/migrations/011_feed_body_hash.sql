@@ -0,0 +1,13 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE feed
+ADD COLUMN last_body_hash TEXT DEFAULT NULL;
+ALTER TABLE feed_fetches
+ADD COLUMN unchanged BOOLEAN NOT NULL DEFAULT false;
+-- +goose StatementEnd
+
+-- +goose down
+ALTER TABLE feed_fetches
+DROP COLUMN unchanged;
+ALTER TABLE feed
+DROP COLUMN last_body_hash;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE feed
+ADD COLUMN first_fetch TEXT DEFAULT NULL;
+-- +goose StatementEnd
+
+-- +goose down
+ALTER TABLE feed
+DROP COLUMN first_fetch;
\ No newline at end of file
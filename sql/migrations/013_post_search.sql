@@ -0,0 +1,38 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE posts
+ADD COLUMN content TEXT NULL;
+ALTER TABLE posts
+ADD COLUMN search_vector TSVECTOR;
+
+-- Titles weigh the most, then descriptions, then the full content
+CREATE FUNCTION posts_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(NEW.description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(NEW.content, '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER posts_search_vector_update
+BEFORE INSERT OR UPDATE OF title, description, content ON posts
+FOR EACH ROW EXECUTE FUNCTION posts_search_vector_update();
+
+-- Fires the trigger for the posts stored so far
+UPDATE posts SET title = title;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+DROP INDEX posts_search_vector_idx;
+DROP TRIGGER posts_search_vector_update ON posts;
+DROP FUNCTION posts_search_vector_update();
+ALTER TABLE posts
+DROP COLUMN search_vector;
+ALTER TABLE posts
+DROP COLUMN content;
+-- +goose StatementEnd
\ No newline at end of file
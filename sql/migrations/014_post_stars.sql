@@ -0,0 +1,16 @@
+-- +goose up
+-- +goose StatementBegin
+CREATE TABLE post_stars(
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    starred_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+-- Pruning old posts looks stars up by post, as starred posts are kept
+CREATE INDEX post_stars_post_id_idx ON post_stars(post_id);
+-- +goose StatementEnd
+
+-- +goose down
+DROP TABLE post_stars;
\ No newline at end of file
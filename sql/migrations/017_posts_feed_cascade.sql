@@ -0,0 +1,13 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey,
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_fkey,
+ADD CONSTRAINT posts_feed_id_fkey FOREIGN KEY (feed_id) REFERENCES feed(id);
+-- +goose StatementEnd
\ No newline at end of file
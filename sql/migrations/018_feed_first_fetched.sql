@@ -0,0 +1,16 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE feed
+ADD COLUMN first_fetched_at TIMESTAMP DEFAULT NULL;
+-- last_fetched_at is also set by failed fetches, only feeds that stored something were fetched before
+UPDATE feed
+SET first_fetched_at = last_fetched_at
+WHERE last_fetched_at IS NOT NULL
+AND (last_body_hash IS NOT NULL OR EXISTS (SELECT 1 FROM posts WHERE posts.feed_id = feed.id));
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+ALTER TABLE feed
+DROP COLUMN first_fetched_at;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose up
+-- +goose StatementBegin
+-- Existing posts are numbered as the column is added
+ALTER TABLE posts
+ADD COLUMN seq BIGSERIAL NOT NULL;
+-- watch asks for the posts after the last one it saw
+CREATE UNIQUE INDEX posts_seq_idx ON posts(seq);
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+DROP INDEX posts_seq_idx;
+ALTER TABLE posts
+DROP COLUMN seq;
+-- +goose StatementEnd
\ No newline at end of file
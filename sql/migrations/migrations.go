@@ -0,0 +1,32 @@
+// Package migrations embeds the goose migrations so the binary can set up and upgrade its own database
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/pressly/goose/v3"
+)
+
+// The Postgres migrations, with their SQLite equivalents in the sqlite directory
+//
+//go:embed *.sql sqlite/*.sql
+var FS embed.FS
+
+// NewProvider returns a goose provider running the embedded migrations for driver (postgres or sqlite) against db
+func NewProvider(driver string, db *sql.DB) (*goose.Provider, error) {
+	switch driver {
+	case "postgres":
+		return goose.NewProvider(goose.DialectPostgres, db, FS)
+	case "sqlite":
+		sqliteFS, err := fs.Sub(FS, "sqlite")
+		if err != nil {
+			return nil, err
+		}
+		return goose.NewProvider(goose.DialectSQLite3, db, sqliteFS)
+	default:
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+}
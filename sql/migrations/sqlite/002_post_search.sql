@@ -0,0 +1,45 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE posts
+ADD COLUMN content TEXT NULL;
+
+-- An FTS5 index over the posts table, kept in sync by the triggers below
+CREATE VIRTUAL TABLE posts_fts USING fts5(
+    title,
+    description,
+    content,
+    content = 'posts',
+    content_rowid = 'rowid',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts(rowid, title, description, content)
+    VALUES (new.rowid, new.title, new.description, new.content);
+END;
+
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, description, content)
+    VALUES ('delete', old.rowid, old.title, old.description, old.content);
+END;
+
+CREATE TRIGGER posts_fts_update AFTER UPDATE OF title, description, content ON posts BEGIN
+    INSERT INTO posts_fts(posts_fts, rowid, title, description, content)
+    VALUES ('delete', old.rowid, old.title, old.description, old.content);
+    INSERT INTO posts_fts(rowid, title, description, content)
+    VALUES (new.rowid, new.title, new.description, new.content);
+END;
+
+-- Index the posts stored so far
+INSERT INTO posts_fts(posts_fts) VALUES ('rebuild');
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+DROP TRIGGER posts_fts_update;
+DROP TRIGGER posts_fts_delete;
+DROP TRIGGER posts_fts_insert;
+DROP TABLE posts_fts;
+ALTER TABLE posts
+DROP COLUMN content;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose up
+-- +goose StatementBegin
+CREATE TABLE follow_tags(
+    feed_follow_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (feed_follow_id, tag),
+    FOREIGN KEY (feed_follow_id) REFERENCES feed_follows(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose down
+DROP TABLE follow_tags;
\ No newline at end of file
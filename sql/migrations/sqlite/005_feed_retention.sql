@@ -0,0 +1,14 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE feed
+ADD COLUMN retention TEXT DEFAULT NULL;
+-- Pruning and browse both look up the posts of a feed newest first
+CREATE INDEX posts_feed_id_published_at_idx ON posts(feed_id, published_at);
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+DROP INDEX posts_feed_id_published_at_idx;
+ALTER TABLE feed
+DROP COLUMN retention;
+-- +goose StatementEnd
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose up
+-- +goose StatementBegin
+-- Databases created before the table had a migration of its own have it from 001
+CREATE TABLE IF NOT EXISTS post_reads(
+    user_id TEXT NOT NULL,
+    post_id TEXT NOT NULL,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+DROP TABLE post_reads;
+-- +goose StatementEnd
\ No newline at end of file
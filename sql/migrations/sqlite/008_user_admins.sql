@@ -0,0 +1,15 @@
+-- +goose up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+-- Admins used to be listed in the config file, the first user registered takes over so someone can grant the rest
+UPDATE users
+SET is_admin = true
+WHERE id = (SELECT id FROM users ORDER BY created_at, name LIMIT 1);
+-- +goose StatementEnd
+
+-- +goose down
+-- +goose StatementBegin
+ALTER TABLE users
+DROP COLUMN is_admin;
+-- +goose StatementEnd
\ No newline at end of file
@@ -1,11 +1,12 @@
 -- name: CreateFeed :one
-INSERT INTO feed(created_at, updated_at, name, url, user_id)
+INSERT INTO feed(created_at, updated_at, name, url, user_id, first_fetch)
 VALUES (
     $1,
     $2,
     $3,
     $4,
-    $5
+    $5,
+    $6
 )
 RETURNING *;
 
@@ -26,6 +27,7 @@ SELECT
     f.name as Name,
     f.url as Url,
     u.name as User,
+    f.user_id,
     f.created_at as Created_at,
     f.updated_at as updated_at
 FROM feed as f
@@ -56,13 +58,24 @@ ON users.id = inserted_feed_follows.user_id;
 SELECT
     feed_follows.*,
     f.name as feed_name,
-    u.name as username
+    u.name as username,
+    (
+        SELECT COUNT(*)
+        FROM posts AS p
+        WHERE p.feed_id = feed_follows.feed_id
+        AND NOT EXISTS (
+            SELECT 1
+            FROM post_reads AS pr
+            WHERE pr.post_id = p.id AND pr.user_id = feed_follows.user_id
+        )
+    ) AS unread
 FROM feed_follows
 INNER JOIN feed as f
 ON f.id = feed_follows.feed_id
 INNER JOIN users as u
 ON u.id = feed_follows.user_id
-WHERE feed_follows.user_id = $1;
+WHERE feed_follows.user_id = $1
+ORDER BY f.name;
 
 -- name: RemoveFollowForUser :exec
 DELETE FROM
@@ -71,17 +84,107 @@ WHERE
 feed_follows.user_id = $1 AND
 feed_follows.feed_id = $2;
 
--- name: MarkFeedFetched :one
+-- name: GetFeedsByUrlOrName :many
+SELECT
+    *
+FROM feed
+WHERE url = $1 OR name = $1
+ORDER BY name;
+
+-- name: GetAllFeeds :many
+SELECT
+    *
+FROM feed
+ORDER BY name;
+
+-- name: ClaimNextFeedToFetch :one
+-- Leases are set and compared by the database clock in UTC, so instances with skewed clocks or other
+-- time zones agree on when one runs out
 UPDATE feed
-SET 
-    updated_at = $1,
-    last_fetched_at = $2
-WHERE id = $3
+SET
+    updated_at = sqlc.arg(now),
+    fetch_lease_until = (now() AT TIME ZONE 'UTC') + sqlc.arg(lease_seconds)::float8 * interval '1 second'
+WHERE id = (
+    SELECT next.id
+    FROM feed AS next
+    WHERE (next.fetch_lease_until IS NULL OR next.fetch_lease_until < now() AT TIME ZONE 'UTC')
+    -- Feeds pushed to us over WebSub are only polled as a fallback
+    AND NOT EXISTS (
+        SELECT 1
+        FROM websub_subscriptions AS ws
+        WHERE ws.feed_id = next.id
+        AND ws.state = 'active'
+        AND ws.lease_expires_at > sqlc.arg(now)
+        AND next.last_fetched_at >= sqlc.arg(pushed_before)
+    )
+    ORDER BY next.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
 RETURNING *;
 
--- name: GetNextFeedToFetch :one
+-- name: ClaimDueFeed :one
+UPDATE feed
+SET
+    updated_at = sqlc.arg(now),
+    fetch_lease_until = (now() AT TIME ZONE 'UTC') + sqlc.arg(lease_seconds)::float8 * interval '1 second'
+WHERE id = (
+    SELECT due.id
+    FROM feed AS due
+    WHERE (due.fetch_lease_until IS NULL OR due.fetch_lease_until < now() AT TIME ZONE 'UTC')
+    AND (due.last_fetched_at IS NULL OR due.last_fetched_at < sqlc.arg(due_before))
+    ORDER BY due.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ClaimFeed :one
+UPDATE feed
+SET
+    updated_at = sqlc.arg(now),
+    fetch_lease_until = (now() AT TIME ZONE 'UTC') + sqlc.arg(lease_seconds)::float8 * interval '1 second'
+WHERE id = sqlc.arg(id)
+AND (fetch_lease_until IS NULL OR fetch_lease_until < now() AT TIME ZONE 'UTC')
+RETURNING *;
+
+-- name: ReleaseFeedLease :exec
+-- last_fetched_at is set after every attempt, failed ones included, so feeds are claimed in turn
+UPDATE feed
+SET
+    last_fetched_at = $2,
+    fetch_lease_until = NULL
+WHERE id = $1;
+
+-- name: SetFeedBodyHash :exec
+UPDATE feed
+SET last_body_hash = $2
+WHERE id = $1;
+
+-- name: SetFeedFirstFetchedAt :exec
+-- Only the first successful fetch is kept, it decides whether the first fetch policy applies
+UPDATE feed
+SET first_fetched_at = $2
+WHERE id = $1 AND first_fetched_at IS NULL;
+
+-- name: GetFeedByID :one
 SELECT
     *
 FROM feed
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: SetFeedRetention :exec
+UPDATE feed
+SET retention = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: CountOtherFollowers :one
+SELECT
+    COUNT(*)
+FROM feed_follows
+WHERE feed_id = $1 AND user_id <> $2;
+
+-- name: DeleteFeed :exec
+-- Its posts, follows and everything else referring to it are deleted with it
+DELETE FROM feed
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- name: SetFeedAuth :one
+INSERT INTO feed_auth(created_at, updated_at, feed_id, kind, name, value)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (feed_id, kind, name) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    value = EXCLUDED.value
+RETURNING *;
+
+-- name: GetFeedAuth :many
+SELECT
+    *
+FROM feed_auth
+WHERE feed_id = $1
+ORDER BY kind, name;
+
+-- name: DeleteFeedAuthKind :exec
+DELETE FROM
+feed_auth
+WHERE
+feed_auth.feed_id = $1 AND
+feed_auth.kind = $2;
+
+-- name: ClearFeedAuth :exec
+DELETE FROM
+feed_auth
+WHERE
+feed_auth.feed_id = $1;
\ No newline at end of file
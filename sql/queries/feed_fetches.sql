@@ -0,0 +1,49 @@
+-- name: CreateFeedFetch :one
+INSERT INTO feed_fetches(
+    feed_id,
+    started_at,
+    duration_ms,
+    http_status,
+    bytes,
+    items_seen,
+    items_inserted,
+    error,
+    unchanged
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetFeedFetchStats :many
+SELECT
+    f.id,
+    f.name,
+    f.url,
+    f.last_fetched_at,
+    COUNT(ff.id) AS fetches,
+    COUNT(ff.id) FILTER (WHERE ff.error IS NULL) AS successes,
+    COUNT(ff.id) FILTER (WHERE ff.unchanged) AS unchanged,
+    CAST(COALESCE(AVG(ff.duration_ms), 0) AS DOUBLE PRECISION) AS avg_duration_ms,
+    CAST(COALESCE(MAX(ff.duration_ms), 0) AS INTEGER) AS max_duration_ms
+FROM feed AS f
+LEFT JOIN feed_fetches AS ff
+ON ff.feed_id = f.id AND ff.started_at >= $1
+GROUP BY f.id, f.name, f.url, f.last_fetched_at
+ORDER BY f.name;
+
+-- name: GetRecentFeedFetches :many
+SELECT
+    *
+FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY started_at DESC
+LIMIT $2;
\ No newline at end of file
@@ -0,0 +1,27 @@
+-- name: CreateFeedResponse :exec
+INSERT INTO feed_responses(feed_id, fetched_at, http_status, headers, body)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: PruneFeedResponses :exec
+DELETE FROM feed_responses
+WHERE feed_responses.feed_id = sqlc.arg(feed_id)
+AND feed_responses.id NOT IN (
+    SELECT kept.id
+    FROM feed_responses AS kept
+    WHERE kept.feed_id = sqlc.arg(feed_id)
+    ORDER BY kept.fetched_at DESC
+    LIMIT sqlc.arg(keep)
+);
+
+-- name: GetFeedResponses :many
+SELECT
+    *
+FROM feed_responses
+WHERE feed_id = $1
+ORDER BY fetched_at ASC;
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- name: TagFollow :exec
+INSERT INTO follow_tags(feed_follow_id, tag)
+SELECT
+    ff.id,
+    $3
+FROM feed_follows AS ff
+WHERE ff.user_id = $1 AND ff.feed_id = $2
+ON CONFLICT (feed_follow_id, tag) DO NOTHING;
+
+-- name: UntagFollow :execrows
+DELETE FROM follow_tags
+WHERE tag = $3
+AND feed_follow_id IN (
+    SELECT ff.id
+    FROM feed_follows AS ff
+    WHERE ff.user_id = $1 AND ff.feed_id = $2
+);
+
+-- name: GetFollowTagsForUser :many
+SELECT
+    ff.feed_id,
+    ft.tag
+FROM follow_tags AS ft
+INNER JOIN feed_follows AS ff
+ON ff.id = ft.feed_follow_id
+INNER JOIN feed AS f
+ON f.id = ff.feed_id
+WHERE ff.user_id = $1
+ORDER BY ft.tag, f.name;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- name: Notify :exec
+SELECT pg_notify(sqlc.arg(channel), sqlc.arg(payload));
\ No newline at end of file
@@ -0,0 +1,70 @@
+-- name: MarkFeedPostsReadForFollowers :exec
+INSERT INTO post_reads(user_id, post_id, read_at)
+SELECT
+    ff.user_id,
+    p.id,
+    $2
+FROM posts AS p
+INNER JOIN feed_follows AS ff
+ON ff.feed_id = p.feed_id
+WHERE p.feed_id = $1
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: FindPostsForUser :many
+-- ref is the url of a post or the start of its id, as shown by browse. Only posts of feeds the user
+-- follows or posts they starred are found, and at most two so an ambiguous id can be told apart
+-- The id is compared as a plain prefix, LIKE would treat % and _ in ref as wildcards
+SELECT
+    p.id,
+    p.title,
+    p.url
+FROM posts AS p
+WHERE (
+    p.feed_id IN (
+        SELECT ff.feed_id
+        FROM feed_follows AS ff
+        WHERE ff.user_id = sqlc.arg(user_id)
+    )
+    OR EXISTS (
+        SELECT 1
+        FROM post_stars AS ps
+        WHERE ps.post_id = p.id AND ps.user_id = sqlc.arg(user_id)
+    )
+)
+AND (
+    p.url = sqlc.arg(ref)
+    OR substr(CAST(p.id AS TEXT), 1, length(sqlc.arg(ref))) = sqlc.arg(ref)
+)
+ORDER BY p.published_at DESC
+LIMIT 2;
+
+-- name: MarkPostRead :exec
+INSERT INTO post_reads(user_id, post_id, read_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: MarkPostsRead :execrows
+-- Marks the posts of the feeds the user follows as read, optionally only those of one feed and
+-- those published before a time
+INSERT INTO post_reads(user_id, post_id, read_at)
+SELECT
+    sqlc.arg(user_id),
+    p.id,
+    sqlc.arg(read_at)
+FROM posts AS p
+WHERE p.feed_id IN (
+    SELECT ff.feed_id
+    FROM feed_follows AS ff
+    WHERE ff.user_id = sqlc.arg(user_id)
+)
+AND (sqlc.narg(feed_id)::UUID IS NULL OR p.feed_id = sqlc.narg(feed_id))
+AND (sqlc.narg(published_before)::TIMESTAMP IS NULL OR p.published_at < sqlc.narg(published_before))
+ON CONFLICT (user_id, post_id) DO NOTHING;
\ No newline at end of file
@@ -1,4 +1,6 @@
--- name: CreatePost :one
+-- name: CreatePosts :many
+-- Inserts the items of one fetch in a single statement, each a position in the arrays. Posts already
+-- stored are skipped, so only the new ones are returned
 INSERT INTO posts(
     created_at,
     updated_at,
@@ -6,28 +8,184 @@ INSERT INTO posts(
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    content
 )
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7
-)
-RETURNING *;
+SELECT
+    sqlc.arg(now),
+    sqlc.arg(now),
+    item.title,
+    item.url,
+    NULLIF(item.description, ''),
+    item.published_at,
+    sqlc.arg(feed_id),
+    NULLIF(item.content, '')
+FROM (
+    -- unnest calls side by side in a select list are zipped together
+    SELECT
+        unnest(sqlc.arg(titles)::TEXT[]) AS title,
+        unnest(sqlc.arg(urls)::TEXT[]) AS url,
+        unnest(sqlc.arg(descriptions)::TEXT[]) AS description,
+        unnest(sqlc.arg(published_ats)::TIMESTAMP[]) AS published_at,
+        unnest(sqlc.arg(contents)::TEXT[]) AS content
+) AS item
+ON CONFLICT (url) DO NOTHING
+RETURNING id, title, url, published_at;
 
 -- name: GetPostsForUser :many
 SELECT 
+    p.id,
     p.title,
     p.url,
     p.description,
-    p.published_at
+    p.published_at,
+    EXISTS (
+        SELECT 1
+        FROM post_reads AS pr
+        WHERE pr.post_id = p.id AND pr.user_id = ff.user_id
+    ) AS read,
+    EXISTS (
+        SELECT 1
+        FROM post_stars AS ps
+        WHERE ps.post_id = p.id AND ps.user_id = ff.user_id
+    ) AS starred
 FROM posts as p
 INNER JOIN feed_follows as ff
 ON ff.feed_id = p.feed_id
-WHERE ff.user_id = $1
+WHERE ff.user_id = sqlc.arg(user_id)
+AND (NOT sqlc.arg(unread_only)::BOOLEAN OR NOT EXISTS (
+    SELECT 1
+    FROM post_reads AS pr
+    WHERE pr.post_id = p.id AND pr.user_id = ff.user_id
+))
+AND (sqlc.narg(folder)::TEXT IS NULL OR EXISTS (
+    SELECT 1
+    FROM follow_tags AS ft
+    WHERE ft.feed_follow_id = ff.id AND ft.tag = sqlc.narg(folder)
+))
 ORDER BY p.published_at DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT sqlc.arg(result_limit);
+
+-- name: GetPostsForUserSince :many
+-- seq only grows, so unlike a time it works as a cursor whatever the clocks of the hosts say
+SELECT
+    p.id,
+    p.seq,
+    p.title,
+    p.url,
+    p.published_at,
+    f.name AS feed_name
+FROM posts as p
+INNER JOIN feed_follows as ff
+ON ff.feed_id = p.feed_id
+INNER JOIN feed as f
+ON f.id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id) AND p.seq > sqlc.arg(after_seq)
+ORDER BY p.seq ASC;
+
+-- name: GetLatestPostSeq :one
+SELECT
+    CAST(COALESCE(MAX(seq), 0) AS BIGINT) AS seq
+FROM posts;
+
+-- name: UpsertPost :exec
+INSERT INTO posts(
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+ON CONFLICT (url) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at,
+    content = EXCLUDED.content
+WHERE posts.feed_id = EXCLUDED.feed_id;
+
+-- name: SearchPosts :many
+-- query uses websearch syntax: "quoted phrases", -excluded words and or between alternatives.
+-- Matches in the title and snippet are wrapped in start_sel and stop_sel
+SELECT
+    p.id,
+    p.url,
+    p.published_at,
+    f.name AS feed_name,
+    CAST(ts_headline(
+        'english',
+        p.title,
+        websearch_to_tsquery('english', sqlc.arg(query)),
+        'HighlightAll=true, StartSel=' || sqlc.arg(start_sel)::TEXT || ', StopSel=' || sqlc.arg(stop_sel)::TEXT
+    ) AS TEXT) AS title,
+    CAST(ts_headline(
+        'english',
+        concat_ws(' ', p.description, p.content),
+        websearch_to_tsquery('english', sqlc.arg(query)),
+        'MaxFragments=2, MaxWords=20, MinWords=8, StartSel=' || sqlc.arg(start_sel)::TEXT || ', StopSel=' || sqlc.arg(stop_sel)::TEXT
+    ) AS TEXT) AS snippet,
+    CAST(ts_rank(p.search_vector, websearch_to_tsquery('english', sqlc.arg(query))) AS DOUBLE PRECISION) AS score
+FROM posts AS p
+INNER JOIN feed AS f
+ON f.id = p.feed_id
+WHERE p.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query))
+AND (sqlc.arg(all_feeds)::BOOLEAN OR EXISTS (
+    SELECT 1
+    FROM feed_follows AS ff
+    WHERE ff.feed_id = p.feed_id AND ff.user_id = sqlc.arg(user_id)
+))
+ORDER BY score DESC, p.published_at DESC
+LIMIT sqlc.arg(result_limit);
+
+-- name: CountPrunablePosts :one
+-- Counts what PrunePosts would delete, for prune --dry-run
+SELECT
+    COUNT(*)
+FROM posts AS p
+WHERE p.feed_id = sqlc.arg(feed_id)
+AND NOT EXISTS (
+    SELECT 1
+    FROM post_stars AS ps
+    WHERE ps.post_id = p.id
+)
+AND (sqlc.narg(published_before)::TIMESTAMP IS NULL OR p.published_at < sqlc.narg(published_before))
+AND (sqlc.narg(keep_latest)::INTEGER IS NULL OR p.id NOT IN (
+    SELECT latest.id
+    FROM posts AS latest
+    WHERE latest.feed_id = sqlc.arg(feed_id)
+    ORDER BY latest.published_at DESC, latest.created_at DESC
+    LIMIT sqlc.narg(keep_latest)
+));
+
+-- name: PrunePosts :execrows
+-- Deletes the posts of a feed published before published_before, or all but the keep_latest newest.
+-- Starred posts are never deleted
+DELETE FROM posts AS p
+WHERE p.feed_id = sqlc.arg(feed_id)
+AND NOT EXISTS (
+    SELECT 1
+    FROM post_stars AS ps
+    WHERE ps.post_id = p.id
+)
+AND (sqlc.narg(published_before)::TIMESTAMP IS NULL OR p.published_at < sqlc.narg(published_before))
+AND (sqlc.narg(keep_latest)::INTEGER IS NULL OR p.id NOT IN (
+    SELECT latest.id
+    FROM posts AS latest
+    WHERE latest.feed_id = sqlc.arg(feed_id)
+    ORDER BY latest.published_at DESC, latest.created_at DESC
+    LIMIT sqlc.narg(keep_latest)
+));
\ No newline at end of file
@@ -0,0 +1,10 @@
+// Package queries embeds the SQLite version of every query, which the SQLite backend runs in place of
+// the Postgres queries sqlc generates into internal/database
+package queries
+
+import "embed"
+
+// The SQLite queries, checked against sql/schema/sqlite by `sqlc vet`
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -0,0 +1,179 @@
+-- name: CreateFeed :one
+INSERT INTO feed(created_at, updated_at, name, url, user_id, first_fetch)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6
+)
+RETURNING *;
+
+-- name: GetFeeds :many
+SELECT 
+    f.name as Name,
+    f.url as Url,
+    u.name as User,
+    f.created_at as Created_at,
+    f.updated_at as updated_at
+FROM feed as f
+INNER JOIN users as u
+ON u.id = f.user_id;
+
+-- name: GetFeedByUrl :one
+SELECT 
+    f.id as ID,
+    f.name as Name,
+    f.url as Url,
+    u.name as User,
+    f.user_id,
+    f.created_at as Created_at,
+    f.updated_at as updated_at
+FROM feed as f
+INNER JOIN users as u
+ON u.id = f.user_id
+WHERE f.url = ?1
+LIMIT 1; 
+
+-- name: CreateFeedFollow :one
+-- SQLite has no data modifying CTEs, the names are looked up in the RETURNING clause instead
+INSERT INTO feed_follows(created_at, updated_at, user_id, feed_id)
+VALUES (
+    ?1, ?2, ?3, ?4
+)
+RETURNING
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    feed_id,
+    (SELECT feed.name FROM feed WHERE feed.id = feed_id) AS feed_name,
+    (SELECT users.name FROM users WHERE users.id = user_id) AS username;
+
+-- name: GetFeedFollowsForUser :many
+SELECT
+    feed_follows.*,
+    f.name as feed_name,
+    u.name as username,
+    (
+        SELECT COUNT(*)
+        FROM posts AS p
+        WHERE p.feed_id = feed_follows.feed_id
+        AND NOT EXISTS (
+            SELECT 1
+            FROM post_reads AS pr
+            WHERE pr.post_id = p.id AND pr.user_id = feed_follows.user_id
+        )
+    ) AS unread
+FROM feed_follows
+INNER JOIN feed as f
+ON f.id = feed_follows.feed_id
+INNER JOIN users as u
+ON u.id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+ORDER BY f.name;
+
+-- name: RemoveFollowForUser :exec
+DELETE FROM
+feed_follows
+WHERE 
+feed_follows.user_id = ?1 AND
+feed_follows.feed_id = ?2;
+
+-- name: GetFeedsByUrlOrName :many
+SELECT
+    *
+FROM feed
+WHERE url = ?1 OR name = ?1
+ORDER BY name;
+
+-- name: GetAllFeeds :many
+SELECT
+    *
+FROM feed
+ORDER BY name;
+
+-- name: ClaimNextFeedToFetch :one
+-- SQLite serialises writers, so the claim needs no row locking
+-- Leases are set and compared by the database clock in UTC like on Postgres
+UPDATE feed
+SET
+    updated_at = ?1,
+    fetch_lease_until = strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || ?2 || ' seconds')
+WHERE id = (
+    SELECT next.id
+    FROM feed AS next
+    -- Feeds pushed to us over WebSub are only polled as a fallback
+    LEFT JOIN websub_subscriptions AS ws
+    ON ws.feed_id = next.id
+    AND ws.state = 'active'
+    AND ws.lease_expires_at > ?1
+    WHERE (next.fetch_lease_until IS NULL OR next.fetch_lease_until < strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    AND (ws.id IS NULL OR next.last_fetched_at IS NULL OR next.last_fetched_at < ?3)
+    ORDER BY next.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: ClaimDueFeed :one
+UPDATE feed
+SET
+    updated_at = ?1,
+    fetch_lease_until = strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || ?2 || ' seconds')
+WHERE id = (
+    SELECT due.id
+    FROM feed AS due
+    WHERE (due.fetch_lease_until IS NULL OR due.fetch_lease_until < strftime('%Y-%m-%d %H:%M:%f', 'now'))
+    AND (due.last_fetched_at IS NULL OR due.last_fetched_at < ?3)
+    ORDER BY due.last_fetched_at ASC NULLS FIRST
+    LIMIT 1
+)
+RETURNING *;
+
+-- name: ClaimFeed :one
+UPDATE feed
+SET
+    updated_at = ?1,
+    fetch_lease_until = strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || ?2 || ' seconds')
+WHERE id = ?3
+AND (fetch_lease_until IS NULL OR fetch_lease_until < strftime('%Y-%m-%d %H:%M:%f', 'now'))
+RETURNING *;
+
+-- name: ReleaseFeedLease :exec
+UPDATE feed
+SET
+    last_fetched_at = ?2,
+    fetch_lease_until = NULL
+WHERE id = ?1;
+
+-- name: SetFeedBodyHash :exec
+UPDATE feed
+SET last_body_hash = ?2
+WHERE id = ?1;
+
+-- name: SetFeedFirstFetchedAt :exec
+UPDATE feed
+SET first_fetched_at = ?2
+WHERE id = ?1 AND first_fetched_at IS NULL;
+
+-- name: GetFeedByID :one
+SELECT
+    *
+FROM feed
+WHERE id = ?1;
+
+-- name: SetFeedRetention :exec
+UPDATE feed
+SET retention = ?2, updated_at = ?3
+WHERE id = ?1;
+
+-- name: CountOtherFollowers :one
+SELECT
+    COUNT(*)
+FROM feed_follows
+WHERE feed_id = ?1 AND user_id <> ?2;
+
+-- name: DeleteFeed :exec
+DELETE FROM feed
+WHERE id = ?1;
\ No newline at end of file
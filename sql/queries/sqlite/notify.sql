@@ -0,0 +1,3 @@
+-- name: Notify :exec
+-- SQLite has no notifications, listeners fall back to polling
+SELECT CAST(?1 AS TEXT) AS channel, CAST(?2 AS TEXT) AS payload;
\ No newline at end of file
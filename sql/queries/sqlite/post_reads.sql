@@ -0,0 +1,66 @@
+-- name: MarkFeedPostsReadForFollowers :exec
+INSERT INTO post_reads(user_id, post_id, read_at)
+SELECT
+    ff.user_id,
+    p.id,
+    ?2
+FROM posts AS p
+INNER JOIN feed_follows AS ff
+ON ff.feed_id = p.feed_id
+WHERE p.feed_id = ?1
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: FindPostsForUser :many
+SELECT
+    p.id,
+    p.title,
+    p.url
+FROM posts AS p
+WHERE (
+    p.feed_id IN (
+        SELECT ff.feed_id
+        FROM feed_follows AS ff
+        WHERE ff.user_id = ?1
+    )
+    OR EXISTS (
+        SELECT 1
+        FROM post_stars AS ps
+        WHERE ps.post_id = p.id AND ps.user_id = ?1
+    )
+)
+-- The id is compared as a plain prefix, LIKE would treat % and _ in ref as wildcards
+AND (
+    p.url = ?2
+    OR substr(CAST(p.id AS TEXT), 1, length(?2)) = ?2
+)
+ORDER BY p.published_at DESC
+LIMIT 2;
+
+-- name: MarkPostRead :exec
+INSERT INTO post_reads(user_id, post_id, read_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = ?1 AND post_id = ?2;
+
+-- name: MarkPostsRead :execrows
+INSERT INTO post_reads(user_id, post_id, read_at)
+SELECT
+    ?1,
+    p.id,
+    ?2
+FROM posts AS p
+WHERE p.feed_id IN (
+    SELECT ff.feed_id
+    FROM feed_follows AS ff
+    WHERE ff.user_id = ?1
+)
+AND (?3 IS NULL OR p.feed_id = ?3)
+AND (?4 IS NULL OR p.published_at < ?4)
+ON CONFLICT (user_id, post_id) DO NOTHING;
\ No newline at end of file
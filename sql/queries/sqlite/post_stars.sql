@@ -0,0 +1,28 @@
+-- name: StarPost :exec
+INSERT INTO post_stars(user_id, post_id, starred_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = ?1 AND post_id = ?2;
+
+-- name: GetStarredPostsForUser :many
+SELECT
+    p.id,
+    p.title,
+    p.url,
+    p.published_at,
+    f.name AS feed_name,
+    ps.starred_at
+FROM post_stars AS ps
+INNER JOIN posts AS p
+ON p.id = ps.post_id
+INNER JOIN feed AS f
+ON f.id = p.feed_id
+WHERE ps.user_id = ?1
+ORDER BY ps.starred_at DESC;
\ No newline at end of file
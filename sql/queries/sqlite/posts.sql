@@ -0,0 +1,184 @@
+-- name: CreatePosts :many
+-- The arrays arrive as JSON arrays, see sqliteArgs. sqlc doesn't see parameters inside json_each,
+-- so they are numbered by hand to match the Postgres version
+INSERT INTO posts(
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content
+)
+SELECT
+    ?1,
+    ?1,
+    title.value,
+    url.value,
+    NULLIF(description.value, ''),
+    published_at.value,
+    ?2,
+    NULLIF(content.value, '')
+FROM json_each(?3) AS title
+INNER JOIN json_each(?4) AS url
+ON url.key = title.key
+INNER JOIN json_each(?5) AS description
+ON description.key = title.key
+INNER JOIN json_each(?6) AS published_at
+ON published_at.key = title.key
+INNER JOIN json_each(?7) AS content
+ON content.key = title.key
+WHERE true
+ON CONFLICT (url) DO NOTHING
+RETURNING id, title, url, published_at;
+
+-- name: GetPostsForUser :many
+SELECT 
+    p.id,
+    p.title,
+    p.url,
+    p.description,
+    p.published_at,
+    EXISTS (
+        SELECT 1
+        FROM post_reads AS pr
+        WHERE pr.post_id = p.id AND pr.user_id = ff.user_id
+    ) AS read,
+    EXISTS (
+        SELECT 1
+        FROM post_stars AS ps
+        WHERE ps.post_id = p.id AND ps.user_id = ff.user_id
+    ) AS starred
+FROM posts as p
+INNER JOIN feed_follows as ff
+ON ff.feed_id = p.feed_id
+WHERE ff.user_id = ?1
+AND (?2 = 0 OR NOT EXISTS (
+    SELECT 1
+    FROM post_reads AS pr
+    WHERE pr.post_id = p.id AND pr.user_id = ff.user_id
+))
+AND (?3 IS NULL OR EXISTS (
+    SELECT 1
+    FROM follow_tags AS ft
+    WHERE ft.feed_follow_id = ff.id AND ft.tag = ?3
+))
+ORDER BY p.published_at DESC
+LIMIT ?4;
+
+-- name: GetPostsForUserSince :many
+SELECT
+    p.id,
+    p.rowid AS seq,
+    p.title,
+    p.url,
+    p.published_at,
+    f.name AS feed_name
+FROM posts as p
+INNER JOIN feed_follows as ff
+ON ff.feed_id = p.feed_id
+INNER JOIN feed as f
+ON f.id = p.feed_id
+WHERE ff.user_id = ?1 AND p.rowid > ?2
+ORDER BY p.rowid ASC;
+
+-- name: GetLatestPostSeq :one
+SELECT
+    CAST(COALESCE(MAX(rowid), 0) AS INTEGER) AS seq
+FROM posts;
+
+-- name: UpsertPost :exec
+INSERT INTO posts(
+    created_at,
+    updated_at,
+    title,
+    url,
+    description,
+    published_at,
+    feed_id,
+    content
+)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8
+)
+ON CONFLICT (url) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at,
+    content = EXCLUDED.content
+WHERE posts.feed_id = EXCLUDED.feed_id;
+
+-- name: SearchPosts :many
+-- ?1 arrives in FTS5 syntax, see sqliteArgs. bm25 ranks better matches lower, so it is negated to
+-- sort the same way as ts_rank
+SELECT
+    p.id,
+    p.url,
+    p.published_at,
+    f.name AS feed_name,
+    CAST(highlight(posts_fts, 0, ?2, ?3) AS TEXT) AS title,
+    -- Like the Postgres version the snippet comes from the description and content, not the title
+    CAST(trim(
+        COALESCE(snippet(posts_fts, 1, ?2, ?3, '...', 12), '') || ' ' ||
+        COALESCE(snippet(posts_fts, 2, ?2, ?3, '...', 12), '')
+    ) AS TEXT) AS snippet,
+    CAST(-bm25(posts_fts, 10.0, 4.0, 1.0) AS REAL) AS score
+FROM posts_fts
+INNER JOIN posts AS p
+ON p.rowid = posts_fts.rowid
+INNER JOIN feed AS f
+ON f.id = p.feed_id
+WHERE posts_fts MATCH ?1
+AND (?4 OR EXISTS (
+    SELECT 1
+    FROM feed_follows AS ff
+    WHERE ff.feed_id = p.feed_id AND ff.user_id = ?5
+))
+ORDER BY score DESC, p.published_at DESC
+LIMIT ?6;
+
+-- name: CountPrunablePosts :one
+SELECT
+    COUNT(*)
+FROM posts AS p
+WHERE p.feed_id = ?1
+AND NOT EXISTS (
+    SELECT 1
+    FROM post_stars AS ps
+    WHERE ps.post_id = p.id
+)
+AND (?2 IS NULL OR p.published_at < ?2)
+AND (?3 IS NULL OR p.id NOT IN (
+    SELECT latest.id
+    FROM posts AS latest
+    WHERE latest.feed_id = ?1
+    ORDER BY latest.published_at DESC, latest.created_at DESC
+    LIMIT ?3
+));
+
+-- name: PrunePosts :execrows
+DELETE FROM posts AS p
+WHERE p.feed_id = ?1
+AND NOT EXISTS (
+    SELECT 1
+    FROM post_stars AS ps
+    WHERE ps.post_id = p.id
+)
+AND (?2 IS NULL OR p.published_at < ?2)
+AND (?3 IS NULL OR p.id NOT IN (
+    SELECT latest.id
+    FROM posts AS latest
+    WHERE latest.feed_id = ?1
+    ORDER BY latest.published_at DESC, latest.created_at DESC
+    LIMIT ?3
+));
\ No newline at end of file
@@ -0,0 +1,31 @@
+-- name: CreateUser :one
+-- The first user registered is an admin
+INSERT INTO users(created_at, updated_at, name, is_admin)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    NOT EXISTS (SELECT 1 FROM users)
+)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE name = ?1
+LIMIT 1;
+
+-- name: GetUsers :many
+SELECT * FROM users;
+
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = ?1, updated_at = ?2
+WHERE name = ?3;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE is_admin;
+
+-- name: ResetUsers :exec
+-- SQLite has no TRUNCATE, the foreign keys cascade the delete instead
+DELETE FROM users;
\ No newline at end of file
@@ -0,0 +1,52 @@
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions(created_at, updated_at, feed_id, hub_url, topic_url, secret, state)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET
+    updated_at = EXCLUDED.updated_at,
+    hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = EXCLUDED.secret,
+    state = EXCLUDED.state
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT
+    *
+FROM websub_subscriptions
+WHERE id = ?1;
+
+-- name: GetWebSubSubscriptionByFeed :one
+SELECT
+    *
+FROM websub_subscriptions
+WHERE feed_id = ?1;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET
+    updated_at = ?1,
+    state = 'active',
+    lease_expires_at = ?2
+WHERE id = ?3;
+
+-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET
+    updated_at = ?1,
+    state = ?2
+WHERE id = ?3;
+
+-- name: GetWebSubSubscriptionsToRenew :many
+SELECT
+    *
+FROM websub_subscriptions
+WHERE state = 'active' AND lease_expires_at < ?1;
\ No newline at end of file
@@ -1,9 +1,11 @@
 -- name: CreateUser :one
-INSERT INTO users(created_at, updated_at, name)
+-- The first user registered is an admin
+INSERT INTO users(created_at, updated_at, name, is_admin)
 VALUES (
     $1,
     $2,
-    $3
+    $3,
+    NOT EXISTS (SELECT 1 FROM users)
 )
 RETURNING *;
 
@@ -15,5 +17,14 @@ LIMIT 1;
 -- name: GetUsers :many
 SELECT * FROM users;
 
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $1, updated_at = $2
+WHERE name = $3;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE is_admin;
+
 -- name: ResetUsers :exec
 TRUNCATE TABLE users CASCADE;
\ No newline at end of file
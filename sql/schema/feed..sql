@@ -6,5 +6,10 @@ CREATE TABLE feed(
     url TEXT NOT NULL UNIQUE,
     user_id UUID NOT NULL,
     last_fetched_at TIMESTAMP DEFAULT NULL,
+    fetch_lease_until TIMESTAMP DEFAULT NULL,
+    last_body_hash TEXT DEFAULT NULL,
+    first_fetch TEXT DEFAULT NULL,
+    retention TEXT DEFAULT NULL,
+    first_fetched_at TIMESTAMP DEFAULT NULL,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
\ No newline at end of file
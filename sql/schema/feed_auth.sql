@@ -0,0 +1,11 @@
+CREATE TABLE feed_auth(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value BYTEA NOT NULL,
+    UNIQUE (feed_id, kind, name),
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
\ No newline at end of file
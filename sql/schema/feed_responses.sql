@@ -0,0 +1,10 @@
+CREATE TABLE feed_responses(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    feed_id UUID NOT NULL,
+    fetched_at TIMESTAMP NOT NULL,
+    http_status INTEGER NOT NULL,
+    headers JSONB NOT NULL,
+    body BYTEA NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+CREATE INDEX feed_responses_feed_id_fetched_at_idx ON feed_responses(feed_id, fetched_at);
\ No newline at end of file
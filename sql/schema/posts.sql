@@ -7,5 +7,11 @@ CREATE TABLE posts(
     description TEXT NULL,
     published_at TIMESTAMP NOT NULL,
     feed_id UUID NOT NULL,
-    FOREIGN KEY (feed_id) REFERENCES feed(id)
-);
\ No newline at end of file
+    content TEXT NULL,
+    -- Maintained by the posts_search_vector_update trigger
+    search_vector TSVECTOR,
+    seq BIGSERIAL NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+CREATE INDEX posts_feed_id_published_at_idx ON posts(feed_id, published_at);
+CREATE UNIQUE INDEX posts_seq_idx ON posts(seq);
\ No newline at end of file
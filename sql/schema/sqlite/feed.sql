@@ -0,0 +1,15 @@
+CREATE TABLE feed(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL,
+    last_fetched_at TIMESTAMP DEFAULT NULL,
+    fetch_lease_until TIMESTAMP DEFAULT NULL,
+    last_body_hash TEXT DEFAULT NULL,
+    first_fetch TEXT DEFAULT NULL,
+    retention TEXT DEFAULT NULL,
+    first_fetched_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE feed_fetches(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    feed_id TEXT NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    http_status INTEGER NULL,
+    bytes INTEGER NOT NULL,
+    items_seen INTEGER NOT NULL,
+    items_inserted INTEGER NOT NULL,
+    error TEXT NULL,
+    unchanged BOOLEAN NOT NULL DEFAULT false,
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+CREATE INDEX feed_fetches_feed_id_started_at_idx ON feed_fetches(feed_id, started_at);
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE feed_responses(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    feed_id TEXT NOT NULL,
+    fetched_at TIMESTAMP NOT NULL,
+    http_status INTEGER NOT NULL,
+    headers BLOB NOT NULL,
+    body BLOB NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+CREATE INDEX feed_responses_feed_id_fetched_at_idx ON feed_responses(feed_id, fetched_at);
\ No newline at end of file
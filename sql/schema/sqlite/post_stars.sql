@@ -0,0 +1,9 @@
+CREATE TABLE post_stars(
+    user_id TEXT NOT NULL,
+    post_id TEXT NOT NULL,
+    starred_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+CREATE INDEX post_stars_post_id_idx ON post_stars(post_id);
\ No newline at end of file
@@ -0,0 +1,26 @@
+CREATE TABLE posts(
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT NULL,
+    published_at TIMESTAMP NOT NULL,
+    feed_id TEXT NOT NULL,
+    content TEXT NULL,
+    -- The implicit rowid, which watch uses as its cursor. Writers take turns, so it grows in the order
+    -- posts are committed. sqlc doesn't know about it, so it is listed as a regular column here
+    rowid INTEGER NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feed(id) ON DELETE CASCADE
+);
+CREATE INDEX posts_feed_id_published_at_idx ON posts(feed_id, published_at);
+
+-- Maintained by the posts_fts_* triggers, see sql/migrations/sqlite/002_post_search.sql for the real
+-- definition. sqlc doesn't know the hidden column FTS5 names after the table, which MATCH and the
+-- auxiliary functions take, so it is listed as a regular column here
+CREATE VIRTUAL TABLE posts_fts USING fts5(
+    title,
+    description,
+    content,
+    posts_fts
+);
\ No newline at end of file
@@ -2,5 +2,6 @@ CREATE TABLE users(
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     created_at TIMESTAMP NOT NULL,
     updated_at TIMESTAMP NOT NULL,
-    name TEXT NOT NULL UNIQUE
+    name TEXT NOT NULL UNIQUE,
+    is_admin BOOLEAN NOT NULL DEFAULT false
 );
\ No newline at end of file